@@ -0,0 +1,44 @@
+package heap
+
+// Adapter exposes a GenericHeap as a container/heap.Interface, so that it
+// can be driven by the standard library's heap functions. Push and Pop are
+// the primitive operations container/heap expects, which append or remove
+// the last element without restoring the ordering; use them only through
+// container/heap.
+type Adapter struct {
+	h *GenericHeap
+}
+
+// NewAdapter returns an Adapter for h, which must not be bounded. It
+// panics if h has LazyDelete, since container/heap would take the
+// tombstones of deleted elements for live ones.
+func NewAdapter(h *GenericHeap) *Adapter {
+	if h.Heap.Lazy {
+		panic("NewAdapter called on a heap with LazyDelete")
+	}
+	return &Adapter{h: h}
+}
+
+func (a *Adapter) Len() int {
+	return len(a.h.Heap.Data)
+}
+
+func (a *Adapter) Less(i, j int) bool {
+	return a.h.Heap.less(i, j)
+}
+
+func (a *Adapter) Swap(i, j int) {
+	a.h.Heap.swap(i, j)
+}
+
+func (a *Adapter) Push(x interface{}) {
+	a.h.AppendUnsorted(x)
+}
+
+func (a *Adapter) Pop() interface{} {
+	n := len(a.h.Heap.Data) - 1
+	out := a.h.Heap.Data[n]
+	a.h.Heap.popped(1)
+	a.h.Heap.truncate(n)
+	return out.Interface()
+}
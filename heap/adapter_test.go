@@ -0,0 +1,36 @@
+package heap
+
+import stdheap "container/heap"
+import "testing"
+
+func TestAdapter(t *testing.T) {
+	g := NewIntHeap()
+	for _, x := range []int{5, 2, 8, 1} {
+		g.AppendUnsorted(x)
+	}
+	a := NewAdapter(g)
+	stdheap.Init(a)
+	stdheap.Push(a, 0)
+	var got []int
+	for a.Len() > 0 {
+		got = append(got, stdheap.Pop(a).(int))
+	}
+	checkOrder(t, got, []int{0, 1, 2, 5, 8})
+}
+
+func TestAdapterPopSeq(t *testing.T) {
+	g := NewIntHeap(CollectStats())
+	g.PushSlice([]int{5, 2, 8, 1})
+	a := NewAdapter(g)
+	stdheap.Pop(a)
+	stdheap.Pop(a)
+	if x, seq := g.PopSeq(); x != 5 || seq != 3 {
+		t.Fatalf("PopSeq() after two pops through the Adapter = %v, %v, want 5, 3", x, seq)
+	}
+}
+
+func TestAdapterLazyDelete(t *testing.T) {
+	g := NewIntHeap(LazyDelete())
+	g.PushSlice([]int{1, 2, 3})
+	mustPanic(t, "NewAdapter of a LazyDelete heap", func() { NewAdapter(g) })
+}
@@ -0,0 +1,58 @@
+package heap
+
+import "fmt"
+import "math/rand"
+import "reflect"
+
+// NewIntHeap returns a heap of ints in ascending order.
+func NewIntHeap(opts ...Option) *GenericHeap {
+	return Define(reflect.TypeOf(0), func(a, b int) bool { return a < b }, opts...)
+}
+
+// NewFloat64Heap returns a heap of float64s in ascending order.
+func NewFloat64Heap(opts ...Option) *GenericHeap {
+	return Define(reflect.TypeOf(0.0), func(a, b float64) bool { return a < b }, opts...)
+}
+
+// NewStringHeap returns a heap of strings in ascending order.
+func NewStringHeap(opts ...Option) *GenericHeap {
+	return Define(reflect.TypeOf(""), func(a, b string) bool { return a < b }, opts...)
+}
+
+// FromSeed returns an int heap of n pseudo-random elements generated from
+// seed, so the same seed always yields the same heap. It is meant for
+// reproducible tests and benchmarks.
+func FromSeed(n int, seed int64, opts ...Option) *GenericHeap {
+	r := rand.New(rand.NewSource(seed))
+	xs := make([]int, n)
+	for i := range xs {
+		xs[i] = r.Int()
+	}
+	h := NewIntHeap(opts...)
+	h.PushSlice(xs)
+	return h
+}
+
+// FromMapValues returns a heap of the values of the map m, ordered by
+// less, which is either a func(a, b interface{}) bool or a typed
+// comparator as accepted by Define. The keys are discarded and the heap
+// is built in O(n).
+func FromMapValues(m interface{}, less interface{}, opts ...Option) *GenericHeap {
+	mv := reflect.ValueOf(m)
+	if mv.Kind() != reflect.Map {
+		panic(fmt.Sprintf("expected a map, got %T", m))
+	}
+
+	var h *GenericHeap
+	if f, ok := less.(func(a, b interface{}) bool); ok {
+		h = NewFunc(mv.Type().Elem(), f, opts...)
+	} else {
+		h = Define(mv.Type().Elem(), less, opts...)
+	}
+	vs := make([]reflect.Value, 0, mv.Len())
+	for it := mv.MapRange(); it.Next(); {
+		vs = append(vs, it.Value())
+	}
+	h.Heap.pushAll(vs)
+	return h
+}
@@ -0,0 +1,52 @@
+package heap
+
+import "fmt"
+import "testing"
+
+func TestBasicHeaps(t *testing.T) {
+	i := NewIntHeap()
+	i.PushBatch(3, 1, 2)
+	f := NewFloat64Heap()
+	f.PushBatch(3.5, 1.5, 2.5)
+	s := NewStringHeap()
+	s.PushBatch("c", "a", "b")
+	got := fmt.Sprint(i.PopN(3), f.PopN(3), s.PopN(3))
+	if want := "[1 2 3] [1.5 2.5 3.5] [a b c]"; got != want {
+		t.Fatalf("popped %v, want %v", got, want)
+	}
+}
+
+func TestFromSeed(t *testing.T) {
+	a, b, c := FromSeed(50, 7), FromSeed(50, 7), FromSeed(50, 8)
+	if a.Len() != 50 {
+		t.Fatalf("FromSeed(50, 7).Len() = %v", a.Len())
+	}
+	if fmt.Sprint(a.Values()) != fmt.Sprint(b.Values()) {
+		t.Fatal("FromSeed with the same seed built different heaps")
+	}
+	if a.Equal(c) {
+		t.Fatal("FromSeed with different seeds built equal heaps")
+	}
+}
+
+func BenchmarkSeededPushPop(b *testing.B) {
+	h := FromSeed(1000, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Push(h.Pop().(int) + 1)
+	}
+}
+
+func TestFromMapValues(t *testing.T) {
+	m := map[string]int{"a": 5, "b": 1, "c": 9, "d": 3}
+	h := FromMapValues(m, func(a, b int) bool { return a < b })
+	var got []int
+	for h.Len() > 0 {
+		got = append(got, h.Pop().(int))
+	}
+	checkOrder(t, got, []int{1, 3, 5, 9})
+	g := FromMapValues(m, func(a, b interface{}) bool { return a.(int) > b.(int) })
+	if x := g.Pop(); x != 9 {
+		t.Fatalf("Pop() with a reversed interface{} less = %v, want 9", x)
+	}
+}
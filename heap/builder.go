@@ -0,0 +1,30 @@
+package heap
+
+import "reflect"
+
+// A Builder collects elements and then turns them into a heap with a
+// single O(n) rebuild.
+type Builder struct {
+	h *GenericHeap
+	vs []reflect.Value
+}
+
+// NewBuilder returns a Builder that adds its elements to h, which is
+// usually a new empty heap from NewFunc, Define or a similar constructor.
+func NewBuilder(h *GenericHeap) *Builder {
+	return &Builder{h: h}
+}
+
+// Add queues x for the heap and returns b so calls can be chained.
+func (b *Builder) Add(x interface{}) *Builder {
+	b.vs = append(b.vs, b.h.Heap.value(x))
+	return b
+}
+
+// Build adds the queued elements to the heap and returns it. The Builder
+// is empty afterwards and can be reused with the same heap.
+func (b *Builder) Build() *GenericHeap {
+	b.h.Heap.pushAll(b.vs)
+	b.vs = nil
+	return b.h
+}
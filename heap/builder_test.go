@@ -0,0 +1,26 @@
+package heap
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	xs := []int{5, 3, 8, 1, 3}
+	b := NewBuilder(NewIntHeap())
+	for _, x := range xs {
+		b.Add(x)
+	}
+	h := b.Build()
+	d := NewIntHeap()
+	for _, x := range xs {
+		d.Push(x)
+	}
+	checkOrder(t, popInts(h), popInts(d))
+
+	// The Builder is empty after Build and adds to the same heap again.
+	if h := b.Add(2).Add(1).Build(); h.Len() != 2 {
+		t.Fatalf("Len() after reusing the Builder = %v, want 2", h.Len())
+	}
+	if n := NewBuilder(NewIntHeap()).Build().Len(); n != 0 {
+		t.Fatalf("Len() of an empty build = %v, want 0", n)
+	}
+	mustPanic(t, "Add of a string to an int heap", func() { NewBuilder(NewIntHeap()).Add("x") })
+}
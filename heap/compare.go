@@ -0,0 +1,156 @@
+package heap
+
+import "fmt"
+import "math/rand"
+import "reflect"
+import "sort"
+
+// Reverse returns a comparator of the same type as less with its operands
+// swapped, turning a min-heap comparator into a max-heap one. It accepts
+// anything Define or NewFunc do, including int-returning comparators.
+func Reverse(less interface{}) interface{} {
+	f := reflect.ValueOf(less)
+	if f.Kind() != reflect.Func || f.Type().NumIn() != 2 {
+		panic(fmt.Sprintf("expected a comparator func, got %T", less))
+	}
+	return reflect.MakeFunc(f.Type(), func(in []reflect.Value) []reflect.Value {
+		return f.Call([]reflect.Value{in[1], in[0]})
+	}).Interface()
+}
+
+// Lexicographic combines comparators of the same element type into a
+// func(a, b T) bool that orders by each in turn: the first one that finds
+// a and b unequal decides. Bool and int-returning comparators may be
+// mixed. Comparators of interface{} values yield a func usable with
+// NewFunc after a type assertion.
+func Lexicographic(lesses ...interface{}) interface{} {
+	if len(lesses) == 0 {
+		panic("Lexicographic needs at least one comparator")
+	}
+	fs := make([]reflect.Value, len(lesses))
+	for i, less := range lesses {
+		fs[i] = reflect.ValueOf(less)
+		if fs[i].Kind() != reflect.Func {
+			panic(fmt.Sprintf("expected a comparator func, got %T", less))
+		}
+		if err := checkLess(fs[i].Type()); err != nil {
+			panic(err.Error())
+		}
+		if fs[i].Type().In(0) != fs[0].Type().In(0) {
+			panic(fmt.Sprintf("comparator %v does not compare %v", fs[i].Type(), fs[0].Type().In(0)))
+		}
+	}
+
+	elem := fs[0].Type().In(0)
+	typ := reflect.FuncOf([]reflect.Type{elem, elem}, []reflect.Type{reflect.TypeOf(false)}, false)
+	return reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		swapped := []reflect.Value{in[1], in[0]}
+		for _, f := range fs {
+			if f.Type().Out(0).Kind() != reflect.Bool {
+				if c := f.Call(in)[0].Int(); c != 0 {
+					return []reflect.Value{reflect.ValueOf(c < 0)}
+				}
+				continue
+			}
+			if f.Call(in)[0].Bool() {
+				return []reflect.Value{reflect.ValueOf(true)}
+			}
+			if f.Call(swapped)[0].Bool() {
+				return []reflect.Value{reflect.ValueOf(false)}
+			}
+		}
+		return []reflect.Value{reflect.ValueOf(false)}
+	}).Interface()
+}
+
+// SameOrder reports whether the comparators a and b sort the elements of
+// samples, a slice, into the same order. Both must be comparators for the
+// element type of samples, of any form accepted by Define, or
+// func(a, b interface{}) bool. samples is not modified.
+func SameOrder(a, b interface{}, samples interface{}) bool {
+	s := reflect.ValueOf(samples)
+	if s.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("expected a slice of samples, got %T", samples))
+	}
+	return reflect.DeepEqual(sortedBy(a, s), sortedBy(b, s))
+}
+
+// sortedBy returns a sorted copy of the slice s, ordered by less.
+func sortedBy(less interface{}, s reflect.Value) interface{} {
+	lessValues := comparator(less, s.Type().Elem())
+	c := reflect.MakeSlice(s.Type(), s.Len(), s.Len())
+	reflect.Copy(c, s)
+	sort.SliceStable(c.Interface(), func(i, j int) bool {
+		return lessValues(c.Index(i), c.Index(j))
+	})
+	return c.Interface()
+}
+
+// comparator checks that less is a comparator for elemType, of any form
+// accepted by Define or func(a, b interface{}) bool, and returns it as a
+// func of reflect.Values.
+func comparator(less interface{}, elemType reflect.Type) func(a, b reflect.Value) bool {
+	f := reflect.ValueOf(less)
+	if f.Kind() != reflect.Func {
+		panic(fmt.Sprintf("expected a comparator func, got %T", less))
+	}
+	if err := checkLess(f.Type()); err != nil {
+		panic(err.Error())
+	}
+	if !elemType.AssignableTo(f.Type().In(0)) {
+		panic(fmt.Sprintf("comparator %v does not compare %v", f.Type(), elemType))
+	}
+
+	isInt := f.Type().Out(0).Kind() != reflect.Bool
+	args := make([]reflect.Value, 2)
+	return func(a, b reflect.Value) bool {
+		args[0], args[1] = a, b
+		res := f.Call(args)[0]
+		if isInt {
+			return res.Int() < 0
+		}
+		return res.Bool()
+	}
+}
+
+// debugSamples is the number of triples CheckLess samples on heaps with
+// the Debug option, and debugPeriod the number of pushes between checks.
+const debugSamples = 100
+const debugPeriod = 1024
+
+// CheckLess tests Less on samples triples of elements picked at random
+// from the heap, and returns an error describing the first violation of
+// a strict weak order it finds: an element less than itself, two
+// elements each less than the other, or a < b and b < c without a < c.
+// A nil result does not prove Less correct, only that no violation was
+// seen.
+func (h *GenericHeap) CheckLess(samples int) error {
+	return h.Heap.checkOrder(samples)
+}
+
+func (h *heap) checkOrder(samples int) error {
+	n := len(h.Data)
+	if n == 0 {
+		return nil
+	}
+	r := rand.New(rand.NewSource(int64(n)))
+	for s := 0; s < samples; s++ {
+		a, b, c := h.Data[r.Intn(n)], h.Data[r.Intn(n)], h.Data[r.Intn(n)]
+		switch ab, bc := h.userLess(a, b), h.userLess(b, c); {
+		case h.userLess(a, a):
+			return fmt.Errorf("Less is not irreflexive: Less(%v, %v) is true", a, a)
+		case ab && h.userLess(b, a):
+			return fmt.Errorf("Less is not asymmetric: Less(%v, %v) and Less(%v, %v) are both true", a, b, b, a)
+		case ab && bc && !h.userLess(a, c):
+			return fmt.Errorf("Less is not transitive: Less(%v, %v) and Less(%v, %v) but not Less(%v, %v)", a, b, b, c, a, c)
+		}
+	}
+	return nil
+}
+
+// debugCheckOrder panics if checkOrder finds Less inconsistent.
+func (h *heap) debugCheckOrder() {
+	if err := h.checkOrder(debugSamples); err != nil {
+		panic(err.Error())
+	}
+}
@@ -0,0 +1,78 @@
+package heap
+
+import "fmt"
+import "reflect"
+import "testing"
+
+// popInts pops every element of h, which must hold ints.
+func popInts(h *GenericHeap) []int {
+	var out []int
+	for h.Len() > 0 {
+		out = append(out, h.Pop().(int))
+	}
+	return out
+}
+
+func TestReverse(t *testing.T) {
+	less := func(a, b interface{}) bool { return a.(int) < b.(int) }
+	for _, tc := range []struct {
+		name string
+		h    *GenericHeap
+	}{
+		{"bool", Define(reflect.TypeOf(0), Reverse(func(a, b int) bool { return a < b }))},
+		{"int", Define(reflect.TypeOf(0), Reverse(func(a, b int) int { return a - b }))},
+		{"interface", NewFunc(reflect.TypeOf(0), Reverse(less).(func(a, b interface{}) bool))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.h.PushBatch(3, 9, 1, 5)
+			checkOrder(t, popInts(tc.h), []int{9, 5, 3, 1})
+		})
+	}
+	mustPanic(t, "Reverse of a non-func", func() { Reverse(3) })
+	mustPanic(t, "Reverse of a unary func", func() { Reverse(func(a int) bool { return a > 0 }) })
+}
+
+func TestLexicographic(t *testing.T) {
+	type T struct{ Pri, Deadline, ID int }
+	less := Lexicographic(
+		func(a, b T) bool { return a.Pri < b.Pri },
+		func(a, b T) int { return a.Deadline - b.Deadline },
+		func(a, b T) bool { return a.ID < b.ID },
+	)
+	h := Define(reflect.TypeOf(T{}), less)
+	h.PushBatch(T{2, 1, 1}, T{1, 5, 3}, T{1, 5, 2}, T{1, 3, 9}, T{2, 0, 7})
+	// {1 5 2} and {1 5 3} tie on the first two comparators.
+	if got, want := fmt.Sprint(h.PopN(10)), "[{1 3 9} {1 5 2} {1 5 3} {2 0 7} {2 1 1}]"; got != want {
+		t.Fatalf("popped %v, want %v", got, want)
+	}
+
+	parity := func(a, b interface{}) bool { return a.(int)%2 < b.(int)%2 }
+	value := func(a, b interface{}) bool { return a.(int) < b.(int) }
+	g := NewFunc(reflect.TypeOf(0), Lexicographic(parity, value).(func(a, b interface{}) bool))
+	g.PushBatch(4, 3, 2, 1)
+	checkOrder(t, popInts(g), []int{2, 4, 1, 3})
+
+	mustPanic(t, "Lexicographic()", func() { Lexicographic() })
+	mustPanic(t, "Lexicographic of mixed element types", func() {
+		Lexicographic(func(a, b int) bool { return a < b }, func(a, b string) bool { return a < b })
+	})
+}
+
+func TestSameOrder(t *testing.T) {
+	samples := []int{5, 3, 9, 1, 3, 7}
+	lt := func(x, y int) bool { return x < y }
+	cmp := func(x, y int) int { return x - y }
+	gt := func(x, y interface{}) bool { return x.(int) > y.(int) }
+	if !SameOrder(lt, cmp, samples) {
+		t.Fatal("SameOrder of < and a three-way comparison = false")
+	}
+	if SameOrder(lt, gt, samples) {
+		t.Fatal("SameOrder of < and > = true")
+	}
+	if !SameOrder(Reverse(lt), gt, samples) {
+		t.Fatal("SameOrder of Reverse(<) and > = false")
+	}
+	if got := fmt.Sprint(samples); got != "[5 3 9 1 3 7]" {
+		t.Fatalf("SameOrder modified the samples to %v", got)
+	}
+}
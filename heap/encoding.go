@@ -0,0 +1,67 @@
+package heap
+
+import "bytes"
+import "encoding/gob"
+import "encoding/json"
+import "errors"
+import "reflect"
+
+// MarshalJSON encodes the elements as a JSON array in heap array order.
+func (h *GenericHeap) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Values())
+}
+
+// UnmarshalJSON replaces the contents of an initialized heap with the
+// elements of a JSON array, restoring the heap ordering afterwards.
+func (h *GenericHeap) UnmarshalJSON(data []byte) error {
+	if h.Heap == nil {
+		return errors.New("cannot unmarshal into a heap before Init")
+	}
+	s := reflect.New(reflect.SliceOf(h.Heap.ElemType))
+	if err := json.Unmarshal(data, s.Interface()); err != nil {
+		return err
+	}
+	h.Heap.load(s.Elem())
+	return nil
+}
+
+// RegisterElemType registers the concrete type of example with gob, so
+// that elements of that type can be encoded inside interface values, such
+// as the Value of a Pair. GobEncode and GobDecode register the element
+// type of a heap themselves when it is concrete.
+func RegisterElemType(example interface{}) {
+	gob.Register(example)
+}
+
+// registerElemType registers the element type of h with gob unless it is
+// an interface type, which has no concrete type to register.
+func (h *heap) registerElemType() {
+	if h.ElemType.Kind() != reflect.Interface {
+		RegisterElemType(reflect.Zero(h.ElemType).Interface())
+	}
+}
+
+// GobEncode encodes the elements as a gob slice in heap array order.
+func (h *GenericHeap) GobEncode() ([]byte, error) {
+	h.Heap.registerElemType()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).EncodeValue(h.Heap.slice()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the contents of an initialized heap with elements
+// encoded by GobEncode, restoring the heap ordering afterwards.
+func (h *GenericHeap) GobDecode(data []byte) error {
+	if h.Heap == nil {
+		return errors.New("cannot decode into a heap before Init")
+	}
+	h.Heap.registerElemType()
+	s := reflect.New(reflect.SliceOf(h.Heap.ElemType))
+	if err := gob.NewDecoder(bytes.NewReader(data)).DecodeValue(s); err != nil {
+		return err
+	}
+	h.Heap.load(s.Elem())
+	return nil
+}
@@ -0,0 +1,95 @@
+package heap
+
+import "bytes"
+import "encoding/gob"
+import "encoding/json"
+import "reflect"
+import "testing"
+
+func TestJSON(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 8, 1})
+	b, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newIntHeap(nil)
+	if err := json.Unmarshal(b, g); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, g.PopAll(), h.PopAll())
+
+	// Unmarshaling restores the heap ordering of an unordered array.
+	if err := json.Unmarshal([]byte("[9,3,7,1]"), g); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, g.PopAll(), []int{1, 3, 7, 9})
+
+	if err := json.Unmarshal([]byte(`["a"]`), g); err == nil {
+		t.Fatal("Unmarshal of a string array into an int heap succeeded")
+	}
+	if err := json.Unmarshal([]byte("[1]"), new(IntHeap)); err == nil {
+		t.Fatal("Unmarshal into a heap before Init succeeded")
+	}
+}
+
+func TestGob(t *testing.T) {
+	h := new(JobHeap)
+	Init(h)
+	h.Push(Job{"a", 3.5})
+	h.Push(Job{"b", 1.25})
+	h.Push(Job{"c", 2})
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(h); err != nil {
+		t.Fatal(err)
+	}
+	g := new(JobHeap)
+	Init(g)
+	if err := gob.NewDecoder(&buf).Decode(g); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for g.Len() > 0 {
+		got = append(got, g.Pop().Name)
+	}
+	if len(got) != 3 || got[0] != "b" || got[1] != "c" || got[2] != "a" {
+		t.Fatalf("decoded heap popped %v, want [b c a]", got)
+	}
+}
+
+type gobA struct{ N int }
+type gobB struct{ S string }
+
+func TestRegisterElemType(t *testing.T) {
+	RegisterElemType(gobA{})
+	RegisterElemType(gobB{})
+	q := NewPriorityQueue()
+	q.Enqueue(gobA{1}, 2)
+	q.Enqueue(gobB{"x"}, 1)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&q.GenericHeap); err != nil {
+		t.Fatal(err)
+	}
+	r := NewPriorityQueue()
+	if err := gob.NewDecoder(&buf).Decode(&r.GenericHeap); err != nil {
+		t.Fatal(err)
+	}
+	if x, y := r.Dequeue(), r.Dequeue(); x != (gobB{"x"}) || y != (gobA{1}) {
+		t.Fatalf("decoded queue dequeued %v, %v, want {x}, {1}", x, y)
+	}
+
+	// A heap with a concrete element type registers it itself.
+	less := func(x, y interface{}) bool { return x.(gobA).N < y.(gobA).N }
+	a := NewFunc(reflect.TypeOf(gobA{}), less)
+	a.PushBatch(gobA{3}, gobA{1})
+	data, err := a.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewFunc(reflect.TypeOf(gobA{}), less)
+	if err := b.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if x := b.Pop(); x != (gobA{1}) {
+		t.Fatalf("Pop() after GobDecode = %v, want {1}", x)
+	}
+}
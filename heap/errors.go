@@ -0,0 +1,15 @@
+package heap
+
+import "errors"
+
+// Errors returned by the error-returning variants of the heap API, wrapped
+// with further detail. Use errors.Is to test for them.
+var (
+	ErrEmpty = errors.New("heap is empty")
+	ErrFull = errors.New("heap full")
+	ErrNil = errors.New("nil element")
+	ErrIndexRange = errors.New("index out of range")
+	ErrTypeMismatch = errors.New("type mismatch")
+	ErrMissingMethod = errors.New("missing method")
+	ErrInitialized = errors.New("heap already initialized")
+)
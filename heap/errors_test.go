@@ -0,0 +1,32 @@
+package heap
+
+import "errors"
+import "testing"
+
+func TestSentinelErrors(t *testing.T) {
+	h := NewIntHeap(MaxSize(1))
+	if x, err := h.PopE(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("PopE() on an empty heap = %v, %v, want ErrEmpty", x, err)
+	}
+	h.Push(1)
+	for _, tc := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"PushE on a full heap", h.PushE(2), ErrFull},
+		{"DecreaseKey(5)", h.DecreaseKey(5, 0), ErrIndexRange},
+		{"DecreaseKey with a string", h.DecreaseKey(0, "x"), ErrTypeMismatch},
+		{"PushE of a string", NewIntHeap().PushE("x"), ErrTypeMismatch},
+		{"InitE without Less", InitE(new(noLessHeap)), ErrMissingMethod},
+		{"InitE without Pop", InitE(new(noPopHeap)), ErrMissingMethod},
+		{"InitE of an int", InitE(3), ErrTypeMismatch},
+	} {
+		if !errors.Is(tc.err, tc.want) {
+			t.Fatalf("%v returned %v, want it to wrap %v", tc.name, tc.err, tc.want)
+		}
+	}
+	if x, err := h.PopE(); err != nil || x != 1 {
+		t.Fatalf("PopE() = %v, %v, want 1, nil", x, err)
+	}
+}
@@ -0,0 +1,46 @@
+package heap
+
+import "time"
+
+// Expiring is an element of an ExpiryHeap.
+type Expiring struct {
+	Expires time.Time
+	Value interface{}
+}
+
+// ExpiryHeap is a ready-made heap of values ordered by expiration time,
+// with the soonest to expire at the root.
+type ExpiryHeap struct {
+	GenericHeap
+	Push func(Expiring)
+	Pop func() (Expiring, bool)
+	Remove func(int) Expiring
+	Peek func() (Expiring, bool)
+}
+
+func (h *ExpiryHeap) Less(a, b Expiring) bool {
+	return a.Expires.Before(b.Expires)
+}
+
+func NewExpiryHeap(opts ...Option) *ExpiryHeap {
+	h := new(ExpiryHeap)
+	Init(h, opts...)
+	return h
+}
+
+// Add pushes value to expire at the given time.
+func (h *ExpiryHeap) Add(value interface{}, expires time.Time) {
+	h.Push(Expiring{Expires: expires, Value: value})
+}
+
+// PopExpired pops every element that expires at or before now and
+// returns them, soonest first.
+func (h *ExpiryHeap) PopExpired(now time.Time) []Expiring {
+	var out []Expiring
+	for _, x := range h.PopWhile(func(x interface{}) bool {
+		return !x.(Expiring).Expires.After(now)
+	}) {
+		out = append(out, x.(Expiring))
+	}
+	return out
+}
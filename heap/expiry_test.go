@@ -0,0 +1,25 @@
+package heap
+
+import "testing"
+import "time"
+
+func TestExpiryHeap(t *testing.T) {
+	h := NewExpiryHeap()
+	base := time.Unix(1000, 0)
+	for i, d := range []int{5, 1, 3, 10, 2} {
+		h.Add(i, base.Add(time.Duration(d)*time.Second))
+	}
+	got := h.PopExpired(base.Add(3 * time.Second))
+	var values []int
+	for _, e := range got {
+		values = append(values, e.Value.(int))
+	}
+	// Values 1, 4 and 2 expire after 1s, 2s and exactly 3s.
+	checkOrder(t, values, []int{1, 4, 2})
+	if h.Len() != 2 {
+		t.Fatalf("Len() after PopExpired = %v, want 2", h.Len())
+	}
+	if got := h.PopExpired(base); got != nil {
+		t.Fatalf("PopExpired before any expiry = %v, want nil", got)
+	}
+}
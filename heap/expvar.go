@@ -0,0 +1,57 @@
+package heap
+
+import "expvar"
+import "fmt"
+import "sync/atomic"
+
+// Expvar publishes the length and operation counts of the heap as the
+// expvar variable name, a JSON object with the keys len, pushes, pops and
+// peak, so they appear on /debug/vars. It implies CollectStats. The heap
+// keeps a copy of the values that it updates atomically as it changes,
+// so the /debug/vars handler can read them while another goroutine uses
+// the heap. The variable is published once the heap is built, so a
+// constructor that fails leaves name free.
+func Expvar(name string) Option {
+	return func(h *heap) error {
+		if expvar.Get(name) != nil {
+			return fmt.Errorf("expvar %q is already published", name)
+		}
+		if h.Counters == nil {
+			h.Counters = new(Stats)
+		}
+		h.VarName = name
+		h.Vars = new(expvars)
+		return nil
+	}
+}
+
+// expvars holds the values published by Expvar.
+type expvars struct {
+	len, pushes, pops, peak atomic.Int64
+}
+
+// publishVars publishes h.Vars as the expvar variable h.VarName.
+func (h *heap) publishVars() {
+	v := h.Vars
+	expvar.Publish(h.VarName, expvar.Func(func() interface{} {
+		return map[string]int64{
+			"len": v.len.Load(),
+			"pushes": v.pushes.Load(),
+			"pops": v.pops.Load(),
+			"peak": v.peak.Load(),
+		}
+	}))
+	h.updateVars()
+}
+
+// updateVars copies the length and counters of h to h.Vars, if it is
+// published.
+func (h *heap) updateVars() {
+	if h.Vars == nil || h.Counters == nil {
+		return
+	}
+	h.Vars.len.Store(int64(h.len()))
+	h.Vars.pushes.Store(int64(h.Counters.Pushes))
+	h.Vars.pops.Store(int64(h.Counters.Pops))
+	h.Vars.peak.Store(int64(h.Counters.PeakLen))
+}
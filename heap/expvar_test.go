@@ -0,0 +1,83 @@
+package heap
+
+import "encoding/json"
+import "expvar"
+import "sync"
+import "testing"
+
+// readExpvar decodes the expvar variable name published by Expvar.
+func readExpvar(t *testing.T, name string) map[string]int {
+	t.Helper()
+	var got map[string]int
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// checkExpvar fails t unless the variable name holds want.
+func checkExpvar(t *testing.T, name string, want map[string]int) {
+	t.Helper()
+	got := readExpvar(t, name)
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("expvar %v = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestExpvar(t *testing.T) {
+	// A heap used by one goroutine can be read from another, as the
+	// /debug/vars handler does, without any wrapper.
+	h := NewIntHeap(Expvar("test_heap"), LazyDelete())
+	checkExpvar(t, "test_heap", map[string]int{"len": 0, "pushes": 0, "pops": 0, "peak": 0})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			_ = expvar.Get("test_heap").String()
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		h.Push(i)
+	}
+	<-done
+	h.RemoveValue(5)
+	h.Pop()
+	checkExpvar(t, "test_heap", map[string]int{"len": 198, "pushes": 200, "pops": 1, "peak": 200})
+	h.Clear()
+	checkExpvar(t, "test_heap", map[string]int{"len": 0})
+
+	s := NewSyncHeap(NewIntHeap(Expvar("test_sync_heap")))
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				s.Push(i)
+				_ = expvar.Get("test_sync_heap").String()
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 300; i++ {
+		s.Pop()
+	}
+	checkExpvar(t, "test_sync_heap", map[string]int{"len": 500, "pushes": 800, "pops": 300, "peak": 800})
+
+	if err := InitE(new(IntHeap), Expvar("test_heap")); err == nil {
+		t.Fatal("Expvar accepted a name that is already published")
+	}
+
+	// A constructor failing on a later option leaves the name free.
+	if err := InitE(new(IntHeap), Expvar("test_retry_heap"), Arity(1)); err == nil {
+		t.Fatal("InitE with Arity(1) succeeded")
+	}
+	if v := expvar.Get("test_retry_heap"); v != nil {
+		t.Fatalf("failed InitE published %v", v)
+	}
+	if err := InitE(new(IntHeap), Expvar("test_retry_heap")); err != nil {
+		t.Fatalf("retrying InitE = %v", err)
+	}
+}
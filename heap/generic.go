@@ -0,0 +1,110 @@
+package heap
+
+import "fmt"
+
+// Heap is a min-heap over a type parameter. It uses the same algorithms as
+// the reflection based GenericHeap, but stores elements natively and calls
+// its comparator directly.
+type Heap[T any] struct {
+	data []T
+	less func(a, b T) bool
+}
+
+func New[T any](less func(a, b T) bool) *Heap[T] {
+	return &Heap[T]{less: less}
+}
+
+func (h *Heap[T]) Len() int {
+	return len(h.data)
+}
+
+func (h *Heap[T]) Push(x T) {
+	h.data = append(h.data, x)
+	h.up(len(h.data) - 1)
+}
+
+func (h *Heap[T]) Pop() T {
+	if len(h.data) == 0 {
+		panic("Pop called on an empty heap")
+	}
+	n := len(h.data) - 1
+	h.swap(0, n)
+	h.down(0, n)
+
+	var zero T
+	out := h.data[n]
+	h.data[n] = zero
+	h.data = h.data[:n]
+	return out
+}
+
+func (h *Heap[T]) Peek() (T, bool) {
+	if len(h.data) == 0 {
+		var zero T
+		return zero, false
+	}
+	return h.data[0], true
+}
+
+func (h *Heap[T]) Remove(i int) T {
+	n := h.checkIndex("Remove", i) - 1
+	if n != i {
+		h.swap(i, n)
+		h.down(i, n)
+		h.up(i)
+	}
+
+	var zero T
+	out := h.data[n]
+	h.data[n] = zero
+	h.data = h.data[:n]
+	return out
+}
+
+func (h *Heap[T]) Fix(i int) {
+	h.down(i, h.checkIndex("Fix", i))
+	h.up(i)
+}
+
+// checkIndex panics, with the same message as the reflection based API,
+// if i is not a valid index for op, and otherwise returns the length.
+func (h *Heap[T]) checkIndex(op string, i int) int {
+	n := len(h.data)
+	if i < 0 || i >= n {
+		panic(fmt.Sprintf("%v index %v out of range for heap of length %v", op, i, n))
+	}
+	return n
+}
+
+func (h *Heap[T]) swap(i, j int) {
+	h.data[i], h.data[j] = h.data[j], h.data[i]
+}
+
+func (h *Heap[T]) up(j int) {
+	for {
+		i := (j - 1) / 2 // parent
+		if i == j || !h.less(h.data[j], h.data[i]) {
+			break
+		}
+		h.swap(i, j)
+		j = i
+	}
+}
+
+func (h *Heap[T]) down(i, n int) {
+	for {
+		j1 := 2*i + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after int overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && !h.less(h.data[j1], h.data[j2]) {
+			j = j2 // = 2*i + 2  // right child
+		}
+		if !h.less(h.data[j], h.data[i]) {
+			break
+		}
+		h.swap(i, j)
+		i = j
+	}
+}
@@ -0,0 +1,73 @@
+package heap
+
+import "math/rand"
+import "sort"
+import "testing"
+
+func TestHeap(t *testing.T) {
+	h := New(func(a, b int) bool { return a < b })
+	if _, ok := h.Peek(); ok {
+		t.Fatal("Peek on an empty heap reported an element")
+	}
+	for _, x := range []int{5, 3, 8, 1, 9, 2} {
+		h.Push(x)
+	}
+	if x, ok := h.Peek(); !ok || x != 1 {
+		t.Fatalf("Peek() = %v, %v, want 1, true", x, ok)
+	}
+	h.Remove(3)
+	h.data[0] = 7
+	h.Fix(0)
+	var got []int
+	for h.Len() > 0 {
+		got = append(got, h.Pop())
+	}
+	if len(got) != 5 || !sort.IntsAreSorted(got) {
+		t.Fatalf("popped %v, want 5 elements in order", got)
+	}
+}
+
+func TestHeapPanics(t *testing.T) {
+	h := New(func(a, b int) bool { return a < b })
+	if r := mustPanic(t, "Pop", func() { h.Pop() }); r != "Pop called on an empty heap" {
+		t.Fatalf("Pop panicked with %v", r)
+	}
+	h.Push(1)
+	h.Push(2)
+	want := "Remove index 2 out of range for heap of length 2"
+	if r := mustPanic(t, "Remove", func() { h.Remove(2) }); r != want {
+		t.Fatalf("Remove panicked with %v, want %v", r, want)
+	}
+	want = "Fix index -1 out of range for heap of length 2"
+	if r := mustPanic(t, "Fix", func() { h.Fix(-1) }); r != want {
+		t.Fatalf("Fix panicked with %v, want %v", r, want)
+	}
+	if h.Len() != 2 {
+		t.Fatalf("Len() = %v after the panics, want 2", h.Len())
+	}
+}
+
+// BenchmarkPushPop compares the type-parameterized Heap with the
+// reflection based GenericHeap on a heap of 1000 ints.
+func BenchmarkPushPop(b *testing.B) {
+	xs := rand.New(rand.NewSource(1)).Perm(1000)
+	b.Run("Generic", func(b *testing.B) {
+		h := New(func(a, b int) bool { return a < b })
+		for _, x := range xs {
+			h.Push(x)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Push(h.Pop())
+		}
+	})
+	b.Run("Reflect", func(b *testing.B) {
+		h := newIntHeap(xs)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Push(h.Pop())
+		}
+	})
+}
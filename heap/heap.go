@@ -4,116 +4,2681 @@
 // and also doesn't require implementing sort.Interface and list operations.
 package heap
 
+import "errors"
 import "fmt"
 import "reflect"
+import "sort"
+import "strings"
+import "time"
 
 /* Private Heap Implementation */
 
+// heap holds the state behind a GenericHeap. Elements are stored as
+// reflect.Values, which refer to large values indirectly, so a swap moves
+// a fixed-size header however large the element type is. Only passing
+// elements to a typed Less copies them, which a Less taking pointer
+// elements avoids.
 type heap struct {
 	Data []reflect.Value
 	LessImpl reflect.Value
+	LessFunc func(a, b interface{}) bool
+	LessCtx func(ctx, a, b interface{}) bool
+	Ctx interface{}
+	LessFast func(a, b reflect.Value) bool
+	LessInt bool
+	SameImpl reflect.Value
+	KeyFunc func(x interface{}) float64
+	TieBreak func(a, b interface{}) bool
+	SameKey func(a, b interface{}) bool
+	Combine func(existing, incoming interface{}) interface{}
+	NoDups bool
+	Identity func(elem, x interface{}) bool
+	MemberKey func(x interface{}) interface{}
+	Members map[interface{}]int
+	args []reflect.Value
+	ElemType reflect.Type
+	Max bool
+	D int
+	Bound int
+	OnEvict func(x interface{})
+	MaxSize int
+	CopyOnPush bool
+	Nils NilPolicy
+	ShrinkFactor int
+	SetIndex func(x interface{}, i int)
+	OnSwap func(i, j int)
+	OnPush func(d time.Duration)
+	OnPop func(d time.Duration)
+	Stable bool
+	Lazy bool
+	Meta []meta
+	NextSeq uint64
+	NumDead int
+	Mods uint64
+	Popped uint64
+	Counters *Stats
+	VarName string
+	Vars *expvars
+	LastCompares int
+	Debug bool
+	DebugPushes int
+}
+
+// meta is the per-element state kept in parallel to Data for stable,
+// lazy deletion and key heaps. It is stored by value rather than in a
+// wrapper around each element, so tracking it costs no allocations beyond
+// the growth of Meta itself and there is nothing to recycle on Pop.
+type meta struct {
+	seq uint64
+	dead bool
+	key float64
+}
+
+// tracksMeta reports whether h keeps Meta in step with Data.
+func (h *heap) tracksMeta() bool {
+	return h.Stable || h.Lazy || h.KeyFunc != nil
+}
+
+// refreshKey recomputes the cached key of the element at index i.
+func (h *heap) refreshKey(i int) {
+	if h.KeyFunc != nil && !(h.ordersNils() && isNil(h.Data[i])) {
+		h.Meta[i].key = h.KeyFunc(h.Data[i].Interface())
+	}
+}
+
+// len returns the number of live elements.
+func (h *heap) len() int {
+	return len(h.Data) - h.NumDead
 }
 
 func (h *heap) less(i, j int) bool {
-	res := h.LessImpl.Call([]reflect.Value{h.Data[i], h.Data[j]})
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("Less panicked comparing %v at index %v with %v at index %v: %v", h.Data[i], i, h.Data[j], j, r))
+		}
+	}()
+
+	if h.KeyFunc != nil {
+		return h.lessKeys(i, j)
+	}
+	if h.lessValues(h.Data[i], h.Data[j]) {
+		return true
+	}
+	if h.Stable && !h.lessValues(h.Data[j], h.Data[i]) {
+		return h.Meta[i].seq < h.Meta[j].seq // equal elements leave in insertion order
+	}
+	return false
+}
+
+// lessKeys is less for heaps ordered by cached keys.
+func (h *heap) lessKeys(i, j int) bool {
+	if h.Counters != nil {
+		h.LastCompares++
+	}
+	a, b := h.Meta[i].key, h.Meta[j].key
+	x, y := h.Data[i], h.Data[j]
+	if h.Max {
+		a, b = b, a
+		x, y = y, x
+	}
+	if less, ok := h.nilLess(x, y); ok {
+		return less
+	}
+	if a != b {
+		return a < b
+	}
+	if h.TieBreak != nil {
+		if h.TieBreak(x.Interface(), y.Interface()) {
+			return true
+		}
+		if h.TieBreak(y.Interface(), x.Interface()) {
+			return false
+		}
+	}
+	return h.Stable && h.Meta[i].seq < h.Meta[j].seq
+}
+
+// lessValues compares two elements in heap order, which is the reverse
+// of the user's Less for max-heaps.
+func (h *heap) lessValues(a, b reflect.Value) bool {
+	if h.Max {
+		a, b = b, a
+	}
+	if h.userLess(a, b) {
+		return true
+	}
+	if h.TieBreak != nil && !h.userLess(b, a) {
+		return h.TieBreak(a.Interface(), b.Interface())
+	}
+	return false
+}
+
+// userLess calls the user's comparator regardless of the heap direction.
+func (h *heap) userLess(a, b reflect.Value) bool {
+	if h.Counters != nil {
+		h.LastCompares++
+	}
+	if less, ok := h.nilLess(a, b); ok {
+		return less
+	}
+	if h.LessFast != nil {
+		return h.LessFast(a, b)
+	}
+	if h.LessCtx != nil {
+		return h.LessCtx(h.Ctx, a.Interface(), b.Interface())
+	}
+	if h.LessFunc != nil {
+		return h.LessFunc(a.Interface(), b.Interface())
+	}
+	h.args[0], h.args[1] = a, b
+	res := h.LessImpl.Call(h.args)
+	if h.LessInt {
+		return res[0].Int() < 0
+	}
 	return res[0].Bool()
 }
 
+// ordersNils reports whether h orders nil elements itself rather than
+// passing them to Less.
+func (h *heap) ordersNils() bool {
+	return h.Nils == NilsFirst || h.Nils == NilsLast
+}
+
+// nilLess compares a and b per the nil policy, reporting false if neither
+// is nil or the policy leaves nils to Less.
+func (h *heap) nilLess(a, b reflect.Value) (less, ok bool) {
+	if !h.ordersNils() {
+		return false, false
+	}
+	an, bn := isNil(a), isNil(b)
+	if !an && !bn {
+		return false, false
+	}
+	if h.Nils == NilsFirst {
+		return an && !bn, true
+	}
+	return !an && bn, true
+}
+
+// isNil reports whether v is a nil value of a nilable kind.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// checkLess verifies that less has the signature func(a, b T) bool or
+// func(a, b T) int, where a negative int means a is less than b.
+func checkLess(less reflect.Type) error {
+	if less.NumIn() != 2 || less.In(0) != less.In(1) || less.NumOut() != 1 {
+		return fmt.Errorf("invalid Less signature %v: expected func(a, b YourType) bool or int", less)
+	}
+	switch less.Out(0).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return nil
+	}
+	return fmt.Errorf("invalid Less signature %v: expected func(a, b YourType) bool or int", less)
+}
+
+// setLessImpl installs a Less method value, avoiding reflect.Call for
+// common element types whose comparator can be called directly.
+func (h *heap) setLessImpl(less reflect.Value) {
+	h.LessImpl = less
+	h.args = make([]reflect.Value, 2)
+	h.LessFast = nil
+	h.LessInt = less.Type().Out(0).Kind() != reflect.Bool
+
+	switch f := less.Interface().(type) {
+	case func(a, b int) bool:
+		h.LessFast = func(a, b reflect.Value) bool { return f(int(a.Int()), int(b.Int())) }
+	case func(a, b int64) bool:
+		h.LessFast = func(a, b reflect.Value) bool { return f(a.Int(), b.Int()) }
+	case func(a, b float64) bool:
+		h.LessFast = func(a, b reflect.Value) bool { return f(a.Float(), b.Float()) }
+	case func(a, b string) bool:
+		h.LessFast = func(a, b reflect.Value) bool { return f(a.String(), b.String()) }
+	}
+}
+
+// directLess returns the Less method of h as a plain func value when it
+// has one of the signatures setLessImpl calls directly. A method value
+// taken through reflect would still allocate its receiver on every call.
+func directLess(h interface{}, lessImpl reflect.Value) reflect.Value {
+	switch l := h.(type) {
+	case interface{ Less(a, b int) bool }:
+		return reflect.ValueOf(l.Less)
+	case interface{ Less(a, b int64) bool }:
+		return reflect.ValueOf(l.Less)
+	case interface{ Less(a, b float64) bool }:
+		return reflect.ValueOf(l.Less)
+	case interface{ Less(a, b string) bool }:
+		return reflect.ValueOf(l.Less)
+	}
+	return lessImpl
+}
+
 func (h *heap) swap(i, j int) {
 	h.Data[i], h.Data[j] = h.Data[j], h.Data[i]
+	h.Mods++
+	if h.Counters != nil {
+		h.Counters.Swaps++
+	}
+	if h.tracksMeta() {
+		h.Meta[i], h.Meta[j] = h.Meta[j], h.Meta[i]
+	}
+	if h.SetIndex != nil {
+		h.SetIndex(h.Data[i].Interface(), i)
+		h.SetIndex(h.Data[j].Interface(), j)
+	}
+	if h.OnSwap != nil {
+		h.OnSwap(i, j)
+	}
+}
+
+// added records the new elements at indices i through j-1, giving them
+// sequence numbers for stable heaps and reporting them to SetIndex.
+func (h *heap) added(i, j int) {
+	h.Mods++
+	for k := i; k < j; k++ {
+		if h.tracksMeta() {
+			if k < len(h.Meta) {
+				if h.Meta[k].dead {
+					h.NumDead-- // a new element overwrites the tombstone
+				}
+				h.Meta[k] = meta{seq: h.NextSeq}
+			} else {
+				h.Meta = append(h.Meta, meta{seq: h.NextSeq})
+			}
+			h.NextSeq++
+			h.refreshKey(k)
+		}
+		if h.SetIndex != nil {
+			h.SetIndex(h.Data[k].Interface(), k)
+		}
+		if h.Members != nil {
+			h.Members[h.MemberKey(h.Data[k].Interface())]++
+		}
+	}
+	if h.Counters != nil && len(h.Data) > h.Counters.PeakLen {
+		h.Counters.PeakLen = len(h.Data)
+	}
+	h.updateVars()
+}
+
+// truncate drops the elements from index n onwards.
+func (h *heap) truncate(n int) {
+	if n < len(h.Data) {
+		h.Mods++
+	}
+	for i := n; i < len(h.Data); i++ {
+		h.removed(h.Data[i])
+		h.Data[i] = reflect.Value{} // release references to old elements
+	}
+	h.Data = h.Data[:n]
+	if h.tracksMeta() {
+		h.Meta = h.Meta[:n]
+	}
+	if h.ShrinkFactor > 0 && n < cap(h.Data)/h.ShrinkFactor {
+		h.shrink()
+	}
+	h.updateVars()
+}
+
+// shrink reallocates the backing array with room for twice the current
+// number of elements.
+func (h *heap) shrink() {
+	data := make([]reflect.Value, len(h.Data), 2*len(h.Data))
+	copy(data, h.Data)
+	h.Data = data
+	if h.tracksMeta() {
+		m := make([]meta, len(h.Meta), 2*len(h.Meta))
+		copy(m, h.Meta)
+		h.Meta = m
+	}
+}
+
+// removed reports an element leaving the heap to the SetIndex hook.
+func (h *heap) removed(v reflect.Value) {
+	h.Mods++
+	if h.SetIndex != nil {
+		h.SetIndex(v.Interface(), -1)
+	}
+	if h.Members != nil {
+		k := h.MemberKey(v.Interface())
+		if h.Members[k]--; h.Members[k] <= 0 {
+			delete(h.Members, k)
+		}
+	}
+}
+
+// checkMods panics if the heap has been modified since Mods was mods,
+// which means a callback iterating over the live heap changed it.
+func (h *heap) checkMods(mods uint64) {
+	if h.Mods != mods {
+		panic("heap modified during iteration")
+	}
+}
+
+// checkRoom returns an error if pushing n more elements would exceed the
+// configured MaxSize.
+func (h *heap) checkRoom(n int) error {
+	if h.MaxSize > 0 && h.len()+n > h.MaxSize {
+		return fmt.Errorf("%w: cannot push %v more element(s) to heap of length %v with MaxSize %v", ErrFull, n, h.len(), h.MaxSize)
+	}
+	return nil
+}
+
+// coalesceIndex returns the index of the live element with the same key
+// as v on heaps with Coalesce, or -1.
+func (h *heap) coalesceIndex(v reflect.Value) int {
+	if h.Combine == nil {
+		return -1
+	}
+	x := v.Interface()
+	for i, d := range h.Data {
+		if h.NumDead > 0 && h.Meta[i].dead {
+			continue
+		}
+		if h.SameKey(d.Interface(), x) {
+			return i
+		}
+	}
+	return -1
+}
+
+// coalesce replaces the element at index i with its combination with v,
+// returning the index it moves to.
+func (h *heap) coalesce(i int, v reflect.Value) int {
+	old := h.Data[i]
+	c := h.own(h.value(h.Combine(old.Interface(), v.Interface())))
+	h.removed(old)
+	h.Data[i] = c
+	h.added(i, i+1)
+	if j := h.down(i, len(h.Data)); j != i {
+		return j
+	}
+	return h.up(i)
+}
+
+// PushE backs a Push field declared as func(YourType) error, which
+// returns an error instead of panicking when the heap is full.
+func (h *heap) PushE(in []reflect.Value) []reflect.Value {
+	err := h.checkRoom(1)
+	if h.Nils == NilsReject && isNil(in[0]) {
+		err = fmt.Errorf("%w of type %v", ErrNil, h.ElemType)
+	}
+	if errors.Is(err, ErrFull) && h.merges(in[0]) {
+		err = nil
+	}
+	if err == nil {
+		h.Push(in)
+	}
+	return []reflect.Value{reflect.ValueOf(&err).Elem()}
+}
+
+// merges reports whether pushing v would leave the number of elements
+// unchanged, because it coalesces into an element with the same key or
+// is a duplicate on a heap with NoDuplicates.
+func (h *heap) merges(v reflect.Value) bool {
+	return h.coalesceIndex(v) >= 0 || h.NoDups && h.indexOf(v) >= 0
+}
+
+// own returns the value to store for an incoming element, which is a
+// fresh copy for heaps with CopyOnPush. For pointer elements the pointee
+// is copied.
+func (h *heap) own(v reflect.Value) reflect.Value {
+	if h.Nils == NilsReject && isNil(v) {
+		panic(fmt.Sprintf("%v of type %v", ErrNil, h.ElemType))
+	}
+	if !h.CopyOnPush {
+		return v
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(v.Elem())
+		return c
+	}
+	c := reflect.New(h.ElemType).Elem()
+	c.Set(v)
+	return c
 }
 
 func (h *heap) Push(in []reflect.Value) []reflect.Value {
-	h.Data = append(h.Data, in[0])
-	h.up(len(h.Data)-1)
+	h.push(in[0])
 	return nil
 }
 
+// push pushes v and returns the index it ends up at, or -1 if a full
+// bounded heap rejects it. On a heap with NoDuplicates, pushing an
+// element already present returns the index of that element.
+func (h *heap) push(v reflect.Value) int {
+	if h.OnPush != nil {
+		defer func(start time.Time) { h.OnPush(time.Since(start)) }(time.Now())
+	}
+	if h.Debug {
+		if h.DebugPushes++; h.DebugPushes%debugPeriod == 0 {
+			h.debugCheckOrder()
+		}
+	}
+	h.LastCompares = 0
+	in := []reflect.Value{h.own(v)}
+	if i := h.coalesceIndex(in[0]); i >= 0 {
+		if h.Counters != nil {
+			h.Counters.Pushes++
+		}
+		return h.coalesce(i, in[0])
+	}
+	if h.NoDups {
+		if i := h.indexOf(in[0]); i >= 0 {
+			return i
+		}
+	}
+	if h.Bound == 0 {
+		if err := h.checkRoom(1); err != nil {
+			panic(err.Error())
+		}
+	}
+	if h.Counters != nil {
+		h.Counters.Pushes++
+	}
+	if h.Bound > 0 && h.len() >= h.Bound {
+		h.skipDead()
+		// Full bounded heap: only admit elements that beat the root,
+		// which is evicted in their place.
+		evicted, i := in[0], -1
+		if h.lessValues(h.Data[0], in[0]) {
+			evicted, i = h.replaceRoot(in[0])
+		} else if h.SetIndex != nil {
+			// Rejected without ever being added, so only the index hook
+			// hears of it; Members does not count it.
+			h.SetIndex(in[0].Interface(), -1)
+		}
+		if h.OnEvict != nil {
+			h.OnEvict(evicted.Interface())
+		}
+		h.updateVars() // the rejected push counts
+		return i
+	}
+	h.Data = append(h.Data, in[0])
+	h.added(len(h.Data)-1, len(h.Data))
+	return h.up(len(h.Data) - 1)
+}
+
 func (h *heap) Pop(in []reflect.Value) []reflect.Value {
+	if h.OnPop != nil {
+		defer func(start time.Time) { h.OnPop(time.Since(start)) }(time.Now())
+	}
+	h.LastCompares = 0
+	h.skipDead()
+	if len(h.Data) == 0 {
+		panic("Pop called on an empty heap")
+	}
+	h.popped(1)
+	return []reflect.Value{h.popRoot()}
+}
+
+func (h *heap) popRoot() reflect.Value {
 	n := len(h.Data) - 1
 	h.swap(0, n)
 	h.down(0, n)
 
 	out := h.Data[n]
-	h.Data = h.Data[:n]
-	return []reflect.Value{out}
+	h.truncate(n)
+	return out
+}
+
+// skipDead pops any lazily deleted elements off the root.
+func (h *heap) skipDead() {
+	for h.NumDead > 0 && h.Meta[0].dead {
+		h.NumDead-- // first, so that truncate sees the new length
+		h.popRoot()
+	}
+}
+
+// popped counts n elements leaving the heap by a pop.
+func (h *heap) popped(n int) {
+	if h.Counters != nil {
+		h.Counters.Pops += n
+	}
+	h.Popped += uint64(n)
+}
+
+// isDead reports whether the element at index i has been lazily deleted.
+func (h *heap) isDead(i int) bool {
+	return h.NumDead > 0 && h.Meta[i].dead
+}
+
+// lazyRemove marks the element at index i as deleted, leaving it in place
+// until it reaches the root or tombstones outnumber live elements.
+func (h *heap) lazyRemove(i int) {
+	h.Meta[i].dead = true
+	h.NumDead++
+	h.Mods++
+	h.updateVars()
+	if h.NumDead > h.len() {
+		h.purge()
+	}
+}
+
+// purge drops all lazily deleted elements.
+func (h *heap) purge() {
+	if h.NumDead > 0 {
+		h.filter(func(v reflect.Value) bool { return false })
+	}
+}
+
+// TryPop backs a Pop field declared as func() (YourType, bool), which
+// reports false instead of panicking when the heap is empty.
+func (h *heap) TryPop(in []reflect.Value) []reflect.Value {
+	if h.len() == 0 {
+		return []reflect.Value{reflect.Zero(h.ElemType), reflect.ValueOf(false)}
+	}
+	return []reflect.Value{h.Pop(nil)[0], reflect.ValueOf(true)}
+}
+
+func (h *heap) Peek(in []reflect.Value) []reflect.Value {
+	h.skipDead()
+	if len(h.Data) == 0 {
+		return []reflect.Value{reflect.Zero(h.ElemType), reflect.ValueOf(false)}
+	}
+	return []reflect.Value{h.Data[0], reflect.ValueOf(true)}
+}
+
+func (h *heap) PopAll(in []reflect.Value) []reflect.Value {
+	return []reflect.Value{h.popN(h.len())}
+}
+
+// popN pops up to n elements into a new []ElemType.
+func (h *heap) popN(n int) reflect.Value {
+	if n > h.len() {
+		n = h.len()
+	}
+	if n < 0 {
+		n = 0
+	}
+	out := reflect.MakeSlice(reflect.SliceOf(h.ElemType), n, n)
+	for i := 0; i < n; i++ {
+		out.Index(i).Set(h.Pop(nil)[0])
+	}
+	return out
+}
+
+func (h *heap) TopK(in []reflect.Value) []reflect.Value {
+	return []reflect.Value{h.clone().popN(in[0].Interface().(int))}
 }
 
 func (h *heap) Remove(in []reflect.Value) []reflect.Value {
 	n := len(h.Data) - 1
 	i := in[0].Interface().(int)
+	if i < 0 || i > n {
+		panic(fmt.Sprintf("Remove index %v out of range for heap of length %v", i, len(h.Data)))
+	}
+	if h.isDead(i) {
+		panic(fmt.Sprintf("Remove index %v refers to a deleted element", i))
+	}
+	// Removing the last element needs no sifting. Otherwise the last
+	// element takes its place; sifting only considers indices below n, so
+	// the removed element parked at n is never moved again. The moved
+	// element goes either down or up, never both.
 	if n != i {
 		h.swap(i, n)
-		h.down(i, n)
-		h.up(i)
+		if h.down(i, n) == i {
+			h.up(i)
+		}
 	}
 
 	out := h.Data[n]
-	h.Data = h.Data[:n]
+	h.truncate(n)
 	return []reflect.Value{out}
 }
 
-func (h *heap) up(j int) {
-	for {
-		i := (j - 1) / 2 // parent
-		if i == j || !h.less(j, i) {
-			break
+// value converts x to a reflect.Value of the element type, panicking if
+// x has a different type.
+func (h *heap) value(x interface{}) reflect.Value {
+	v, err := h.checkValue(x)
+	if err != nil {
+		panic(err.Error())
+	}
+	return v
+}
+
+// checkValue is like value but returns an error wrapping ErrTypeMismatch.
+func (h *heap) checkValue(x interface{}) (reflect.Value, error) {
+	v := reflect.New(h.ElemType).Elem()
+	if x == nil {
+		if !isNil(v) { // the zero value of a nilable kind is nil
+			return v, fmt.Errorf("%w: expected %v, got nil", ErrTypeMismatch, h.ElemType)
 		}
-		h.swap(i, j)
-		j = i
+		return v, nil
+	}
+	xv := reflect.ValueOf(x)
+	if !xv.Type().AssignableTo(h.ElemType) {
+		return v, fmt.Errorf("%w: expected %v, got %v", ErrTypeMismatch, h.ElemType, xv.Type())
 	}
+	v.Set(xv)
+	return v, nil
 }
 
-func (h *heap) down(i, n int) {
-	for {
-		j1 := 2*i + 1
-		if j1 >= n || j1 < 0 { // j1 < 0 after int overflow
-			break
+// values copies the elements of a slice of the element type.
+func (h *heap) values(s reflect.Value) []reflect.Value {
+	if !s.IsValid() {
+		return nil // an untyped nil holds no elements
+	}
+	if s.Kind() != reflect.Slice || !s.Type().Elem().AssignableTo(h.ElemType) {
+		panic(fmt.Sprintf("type mismatch: expected a slice of %v, got %v", h.ElemType, s.Type()))
+	}
+	c := reflect.MakeSlice(reflect.SliceOf(h.ElemType), s.Len(), s.Len())
+	reflect.Copy(c, s)
+
+	vs := make([]reflect.Value, c.Len())
+	for i := range vs {
+		vs[i] = c.Index(i)
+	}
+	return vs
+}
+
+// sorted returns a copy of vs sorted by the user's Less.
+func (h *heap) sorted(vs []reflect.Value) []reflect.Value {
+	out := append([]reflect.Value(nil), vs...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return h.userLess(out[i], out[j])
+	})
+	return out
+}
+
+// equal reports whether neither element is less than the other.
+func (h *heap) equal(a, b reflect.Value) bool {
+	return !h.lessValues(a, b) && !h.lessValues(b, a)
+}
+
+// same reports whether a and b are the same element, using the user's
+// Same method if there is one and Less-equality otherwise.
+func (h *heap) same(a, b reflect.Value) bool {
+	if h.SameImpl.IsValid() {
+		return h.SameImpl.Call([]reflect.Value{a, b})[0].Bool()
+	}
+	return h.equal(a, b)
+}
+
+func (h *heap) indexOf(v reflect.Value) int {
+	if h.Members != nil && h.Members[h.MemberKey(v.Interface())] == 0 {
+		return -1
+	}
+	for i, d := range h.Data {
+		if h.NumDead > 0 && h.Meta[i].dead {
+			continue
 		}
-		j := j1 // left child
-		if j2 := j1 + 1; j2 < n && !h.less(j1, j2) {
-			j = j2 // = 2*i + 2  // right child
+		if h.same(d, v) {
+			return i
 		}
-		if !h.less(j, i) {
-			break
+	}
+	return -1
+}
+
+// removalIndex returns the index of the element RemoveValue should remove
+// for v. Data is in level order, so the first match is the closest to the
+// root.
+func (h *heap) removalIndex(v reflect.Value) int {
+	if h.Identity == nil {
+		return h.indexOf(v)
+	}
+	for i, d := range h.Data {
+		if h.NumDead > 0 && h.Meta[i].dead {
+			continue
+		}
+		if h.same(d, v) && h.Identity(d.Interface(), v.Interface()) {
+			return i
 		}
-		h.swap(i, j)
-		i = j
 	}
+	return -1
 }
 
-/* Public List Interface */
+func (h *heap) changeKey(i int, v reflect.Value, increase bool) error {
+	n := len(h.Data)
+	if i < 0 || i >= n {
+		return fmt.Errorf("%w: index %v for heap of length %v", ErrIndexRange, i, n)
+	}
+	if h.isDead(i) {
+		return fmt.Errorf("%w: index %v refers to a deleted element", ErrIndexRange, i)
+	}
+	if increase && h.userLess(v, h.Data[i]) {
+		return fmt.Errorf("IncreaseKey: new value %v is less than current value %v", v, h.Data[i])
+	}
+	if !increase && h.userLess(h.Data[i], v) {
+		return fmt.Errorf("DecreaseKey: new value %v is greater than current value %v", v, h.Data[i])
+	}
 
-type GenericHeap struct {
-	Heap *heap
+	h.removed(h.Data[i])
+	h.Data[i] = h.own(v)
+	h.added(i, i+1)
+	if increase != h.Max {
+		h.down(i, n)
+	} else {
+		h.up(i)
+	}
+	return nil
 }
 
-func (h *GenericHeap) Len() int {
-	return len(h.Heap.Data)
+// replaceRoot replaces the root with v, returning the old root and the
+// index v moves to.
+func (h *heap) replaceRoot(v reflect.Value) (reflect.Value, int) {
+	out := h.Data[0]
+	h.removed(out)
+	h.Data[0] = v
+	h.added(0, 1)
+	return out, h.down(0, len(h.Data))
 }
 
-func getGenericFunc(l reflect.Value, name string)  (func([]reflect.Value) []reflect.Value) {
-	return l.MethodByName(name).Interface().(func([]reflect.Value) []reflect.Value)
+// filter removes the elements for which drop returns true, along with
+// any lazily deleted ones, and rebuilds the heap once, returning the
+// removed live elements.
+func (h *heap) filter(drop func(v reflect.Value) bool) []reflect.Value {
+	var out, dead []reflect.Value
+	n := 0
+	for i, v := range h.Data {
+		if h.NumDead > 0 && h.Meta[i].dead {
+			dead = append(dead, v)
+			continue
+		}
+		if drop(v) {
+			out = append(out, v)
+			continue
+		}
+		h.Data[n] = v
+		if h.tracksMeta() {
+			h.Meta[n] = h.Meta[i]
+		}
+		if h.SetIndex != nil && n != i {
+			h.SetIndex(v.Interface(), n)
+		}
+		n++
+	}
+	for i := n; i < len(h.Data); i++ {
+		h.Data[i] = reflect.Value{}
+	}
+	h.Data = h.Data[:n]
+	if h.tracksMeta() {
+		h.Meta = h.Meta[:n]
+	}
+	h.NumDead = 0
+	for _, v := range append(out, dead...) {
+		h.removed(v)
+	}
+	h.updateVars()
+	h.heapify()
+	return out
 }
 
-func Init(h interface{}) {
-	impl := new(heap)
-	implValue := reflect.ValueOf(impl)
+func (h *heap) compact() {
+	data := make([]reflect.Value, len(h.Data))
+	copy(data, h.Data)
+	h.Data = data
+	if h.tracksMeta() {
+		h.Meta = append([]meta(nil), h.Meta...)
+	}
+}
 
-	obj := reflect.ValueOf(h).Elem()
-	obj.FieldByName("Heap").Set(implValue)
+// writeTree writes the subtree rooted at index i for Tree.
+func (h *heap) writeTree(b *strings.Builder, i, depth int) {
+	if i >= len(h.Data) {
+		return
+	}
+	fmt.Fprintf(b, "%v%v\n", strings.Repeat("  ", depth), h.Data[i])
+	if i >= h.firstLeaf() {
+		return // checked first as D*i+1 could overflow
+	}
+	first := h.D*i + 1
+	for j := first; j-first < h.D && j < len(h.Data); j++ {
+		h.writeTree(b, j, depth+1)
+	}
+}
 
-	for _, fieldName := range []string{"Push", "Pop", "Remove"} {
-		orig := obj.FieldByName(fieldName)
-		if !orig.IsValid() {
-			panic(fmt.Sprintf("Expected a definition for %v on GenericHeap", fieldName))
+// smallest reports which elements are the n first in heap order, for
+// 0 < n <= len(h.Data), using quickselect over their indices in expected
+// O(len(h.Data)) time. Data itself is not reordered.
+func (h *heap) smallest(n int) []bool {
+	idx := make([]int, len(h.Data))
+	for i := range idx {
+		idx[i] = i
+	}
+	k := n - 1
+	lo, hi := 0, len(idx)-1
+	for lo < hi {
+		p := idx[lo+(hi-lo)/2]
+		i, j := lo, hi
+		for i <= j {
+			for h.less(idx[i], p) {
+				i++
+			}
+			for h.less(p, idx[j]) {
+				j--
+			}
+			if i <= j {
+				idx[i], idx[j] = idx[j], idx[i]
+				i++
+				j--
+			}
+		}
+		if k <= j {
+			hi = j
+		} else if k >= i {
+			lo = i
+		} else {
+			break
 		}
-		generic := getGenericFunc(implValue, fieldName)
-		orig.Set(reflect.MakeFunc(orig.Type(), generic))
 	}
 
-	impl.LessImpl = obj.Addr().MethodByName("Less")
-	if !impl.LessImpl.IsValid() {
-		panic("Expected implemention of func (*YourHeap) Less(a, b YourType) int")
+	take := make([]bool, len(h.Data))
+	for _, i := range idx[:n] {
+		take[i] = true
+	}
+	return take
+}
+
+// firstLeaf returns the index of the first element without children.
+func (h *heap) firstLeaf() int {
+	n := len(h.Data)
+	if n < 2 {
+		return 0
+	}
+	return (n-2)/h.D + 1
+}
+
+// leafExtreme returns the index of the last element in heap order, which
+// is always a leaf.
+func (h *heap) leafExtreme() int {
+	j := h.firstLeaf()
+	for i := j + 1; i < len(h.Data); i++ {
+		if h.less(j, i) {
+			j = i
+		}
+	}
+	return j
+}
+
+// extreme returns the largest element per Less if max is set and the
+// smallest otherwise. One of them is the root and the other a leaf.
+func (h *heap) extreme(max bool) interface{} {
+	if len(h.Data) == 0 {
+		return nil
+	}
+	if max == h.Max {
+		return h.Data[0].Interface()
+	}
+	return h.Data[h.leafExtreme()].Interface()
+}
+
+// clone returns a copy of h with its own backing array.
+func (h *heap) clone() *heap {
+	c := *h
+	c.SetIndex = nil // the elements' positions are tracked in h only
+	c.VarName, c.Vars = "", nil // only h is published
+	c.OnSwap = nil
+	c.OnPush, c.OnPop, c.OnEvict = nil, nil, nil
+	c.args = make([]reflect.Value, 2)
+	c.Data = make([]reflect.Value, len(h.Data))
+	copy(c.Data, h.Data)
+	c.Meta = append([]meta(nil), h.Meta...)
+	if h.Members != nil {
+		c.Members = make(map[interface{}]int, len(h.Members))
+		for k, n := range h.Members {
+			c.Members[k] = n
+		}
+	}
+	if h.Counters != nil {
+		counters := *h.Counters
+		c.Counters = &counters
 	}
+	return &c
+}
+
+// emptyClone returns a heap configured like h but holding no elements.
+func (h *heap) emptyClone() *heap {
+	c := h.clone()
+	c.Data, c.Meta, c.NumDead = nil, nil, 0
+	if c.Members != nil {
+		c.Members = make(map[interface{}]int)
+	}
+	if c.Counters != nil {
+		c.Counters = &Stats{}
+	}
+	return c
+}
+
+// slice copies the elements into a new []ElemType in heap array order.
+func (h *heap) slice() reflect.Value {
+	h.purge()
+	out := reflect.MakeSlice(reflect.SliceOf(h.ElemType), len(h.Data), len(h.Data))
+	for i, v := range h.Data {
+		out.Index(i).Set(v)
+	}
+	return out
+}
+
+// load replaces the contents of h with the elements of slice, which the
+// heap takes ownership of.
+func (h *heap) load(slice reflect.Value) {
+	h.Data = make([]reflect.Value, slice.Len())
+	for i := range h.Data {
+		h.Data[i] = slice.Index(i)
+	}
+	h.Meta = nil
+	h.NumDead = 0
+	if h.Members != nil {
+		h.Members = make(map[interface{}]int)
+	}
+	h.added(0, len(h.Data))
+	h.heapify()
+}
+
+// swapContents replaces the elements with vs, rebuilding the heap once,
+// and returns the old elements as a []ElemType in heap array order.
+func (h *heap) swapContents(vs []reflect.Value) reflect.Value {
+	// Check the new size first, so a failed swap leaves the old elements.
+	if h.MaxSize > 0 && len(vs) > h.MaxSize {
+		panic(fmt.Sprintf("%v: cannot swap in %v elements with MaxSize %v", ErrFull, len(vs), h.MaxSize))
+	}
+	old := h.slice() // purges, so no tombstones are left behind
+	h.truncate(0)
+	h.pushAll(vs)
+	return old
+}
+
+// pushAll adds vs to the heap, rebuilding it once rather than sifting
+// each element up.
+func (h *heap) pushAll(vs []reflect.Value) {
+	if len(vs) == 0 {
+		return
+	}
+	if h.Bound > 0 || h.Combine != nil || h.NoDups {
+		for _, v := range vs {
+			h.Push([]reflect.Value{v})
+		}
+		return
+	}
+	if err := h.checkRoom(len(vs)); err != nil {
+		panic(err.Error())
+	}
+	if h.Counters != nil {
+		h.Counters.Pushes += len(vs)
+	}
+	for _, v := range vs {
+		h.Data = append(h.Data, h.own(v))
+	}
+	h.added(len(h.Data)-len(vs), len(h.Data))
+	h.heapify()
+}
+
+func (h *heap) validate() error {
+	n := len(h.Data)
+	for j := 1; j < n; j++ {
+		i := (j - 1) / h.D // parent, computed this way to avoid overflow
+		if h.less(j, i) {
+			return fmt.Errorf("heap invariant violated: child %v at index %v is less than parent %v at index %v", h.Data[j], j, h.Data[i], i)
+		}
+	}
+	return nil
+}
+
+func (h *heap) heapify() {
+	if h.Debug {
+		h.debugCheckOrder()
+	}
+	n := len(h.Data)
+	for i := n/2 - 1; i >= 0; i-- {
+		h.down(i, n)
+	}
+}
+
+// up moves the element at index j towards the root and returns its new
+// index.
+func (h *heap) up(j int) int {
+	for {
+		// j-1 cannot overflow for j >= 0, and for j == 0 it truncates to
+		// the root itself, which ends the loop.
+		i := (j - 1) / h.D // parent
+		if i == j || !h.less(j, i) {
+			break
+		}
+		h.swap(i, j)
+		j = i
+	}
+	return j
+}
+
+// down moves the element at index i towards the leaves and returns its
+// new index.
+func (h *heap) down(i, n int) int {
+	for {
+		// Check for children before computing D*i+1, which can overflow
+		// and, for D > 2, wrap around to a positive index.
+		if n < 2 || i > (n-2)/h.D {
+			break
+		}
+		j1 := h.D*i + 1
+		j := j1 // first child
+		for j2 := j1 + 1; j2-j1 < h.D && j2 < n; j2++ {
+			if !h.less(j, j2) {
+				j = j2 // smallest child so far
+			}
+		}
+		if !h.less(j, i) {
+			break
+		}
+		h.swap(i, j)
+		i = j
+	}
+	return i
+}
+
+/* Public List Interface */
+
+type GenericHeap struct {
+	Heap *heap
+}
+
+func (h *GenericHeap) Len() int {
+	return h.Heap.len()
+}
+
+// Push, Pop, Peek and Remove are the untyped counterparts of the fields wired
+// by Init, for use on heaps without a user struct such as a Clone.
+func (h *GenericHeap) Push(x interface{}) {
+	h.Heap.Push([]reflect.Value{h.Heap.value(x)})
+}
+
+// PushIndexed is like Push but returns the index x ends up at, or -1 if
+// a full bounded heap rejects it. The index is only valid until the heap
+// is next modified.
+func (h *GenericHeap) PushIndexed(x interface{}) int {
+	return h.Heap.push(h.Heap.value(x))
+}
+
+// PushE is like Push but returns an error if the heap is full.
+func (h *GenericHeap) PushE(x interface{}) error {
+	v, err := h.Heap.checkValue(x)
+	if err != nil {
+		return err
+	}
+	if out := h.Heap.PushE([]reflect.Value{v})[0]; !out.IsNil() {
+		return out.Interface().(error)
+	}
+	return nil
+}
+
+func (h *GenericHeap) Pop() interface{} {
+	return h.Heap.Pop(nil)[0].Interface()
+}
+
+// PopInto pops the root and stores it in *dst, which must be a non-nil
+// pointer to a type the element type is assignable to. Unlike Pop it
+// does not convert the element to interface{}, which allocates for most
+// element types.
+func (h *GenericHeap) PopInto(dst interface{}) {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || !h.Heap.ElemType.AssignableTo(ptr.Type().Elem()) {
+		panic(fmt.Sprintf("PopInto: expected a non-nil pointer to %v, got %T", h.Heap.ElemType, dst))
+	}
+	ptr.Elem().Set(h.Heap.Pop(nil)[0])
+}
+
+// PopSeq is like Pop but also returns the sequence number of the pop,
+// which counts the elements popped from the heap over its lifetime,
+// starting at 1 for the first. Pop, PopMax, TakeN and the methods built
+// on them, such as TryPop, PopN or Drain, all count; Remove and its
+// relatives do not. Stats.Pops counts the same pops.
+func (h *GenericHeap) PopSeq() (interface{}, uint64) {
+	x := h.Pop()
+	return x, h.Heap.Popped
+}
+
+// PopMax removes and returns the largest element per Less, which for a
+// min-heap is found among the leaves in O(n) and removed in O(log n).
+func (h *GenericHeap) PopMax() interface{} {
+	if h.Heap.Max {
+		return h.Pop()
+	}
+	h.Heap.purge()
+	if len(h.Heap.Data) == 0 {
+		panic("PopMax called on an empty heap")
+	}
+	h.Heap.popped(1)
+	return h.Remove(h.Heap.leafExtreme())
+}
+
+// Replace pops the root and pushes x with a single sift, returning the
+// old root. The heap must not be empty.
+func (h *GenericHeap) Replace(x interface{}) interface{} {
+	v := h.Heap.value(x)
+	h.Heap.skipDead()
+	if len(h.Heap.Data) == 0 {
+		panic("Replace called on an empty heap")
+	}
+	if h.Heap.merges(v) {
+		// x may match an element other than the root, so pop first and
+		// push x as Push would.
+		out := h.Heap.popRoot()
+		h.Heap.push(v)
+		return out.Interface()
+	}
+	out, _ := h.Heap.replaceRoot(h.Heap.own(v))
+	return out.Interface()
+}
+
+// PushPop pushes x and then pops the root, returning x itself without
+// touching the heap if it would have been the new root.
+func (h *GenericHeap) PushPop(x interface{}) interface{} {
+	v := h.Heap.value(x)
+	h.Heap.skipDead()
+	if h.Heap.merges(v) {
+		h.Heap.push(v)
+		return h.Heap.popRoot().Interface()
+	}
+	if len(h.Heap.Data) > 0 && h.Heap.lessValues(h.Heap.Data[0], v) {
+		out, _ := h.Heap.replaceRoot(h.Heap.own(v))
+		return out.Interface()
+	}
+	return x
+}
+
+// WouldBeRoot reports whether x would become the root if pushed, which
+// is when it is less than the current root or the heap is empty.
+func (h *GenericHeap) WouldBeRoot(x interface{}) bool {
+	v := h.Heap.value(x)
+	h.Heap.skipDead()
+	return len(h.Heap.Data) == 0 || h.Heap.lessValues(v, h.Heap.Data[0])
+}
+
+// PopN pops up to n elements and returns them in order as a slice of the
+// element type.
+func (h *GenericHeap) PopN(n int) interface{} {
+	return h.Heap.popN(n).Interface()
+}
+
+// PopAll pops every element and returns them in order as a slice of the
+// element type, leaving the heap empty.
+func (h *GenericHeap) PopAll() interface{} {
+	return h.Heap.popN(h.Heap.len()).Interface()
+}
+
+// PopE is like Pop but returns ErrEmpty if the heap is empty.
+func (h *GenericHeap) PopE() (interface{}, error) {
+	x, ok := h.TryPop()
+	if !ok {
+		return nil, ErrEmpty
+	}
+	return x, nil
+}
+
+// TopK returns the k elements that would be popped first, or all of them
+// if k exceeds Len(), as a slice of the element type in heap order. The
+// heap is unchanged.
+func (h *GenericHeap) TopK(k int) interface{} {
+	return h.Heap.clone().popN(k).Interface()
+}
+
+// KLargest returns the k elements that would be popped last, which are
+// the k largest of a min-heap, as a slice of the element type in reverse
+// heap order. The heap is unchanged. It runs in O(n log k).
+func (h *GenericHeap) KLargest(k int) interface{} {
+	if k > h.Heap.len() {
+		k = h.Heap.len()
+	}
+	if k < 0 {
+		k = 0
+	}
+	out := reflect.MakeSlice(reflect.SliceOf(h.Heap.ElemType), k, k)
+	if k == 0 {
+		return out.Interface()
+	}
+
+	// Keep the k last elements in a bounded heap with the same ordering,
+	// whose root is the first of them to be popped.
+	b := h.Heap.clone()
+	b.Data, b.Meta, b.NumDead = nil, nil, 0
+	b.Members = nil
+	b.Bound, b.MaxSize, b.Counters, b.Vars = k, 0, nil, nil
+	b.CopyOnPush, b.Combine = false, nil
+	for i, v := range h.Heap.Data {
+		if h.Heap.NumDead == 0 || !h.Heap.Meta[i].dead {
+			b.Push([]reflect.Value{v})
+		}
+	}
+	for i := k - 1; i >= 0; i-- {
+		out.Index(i).Set(b.Pop(nil)[0])
+	}
+	return out.Interface()
+}
+
+// TakeN removes the n first elements in heap order, or all of them if
+// there are fewer, and returns them in no particular order. When n is
+// large it selects them in O(Len()) instead of popping them one by one.
+func (h *GenericHeap) TakeN(n int) []interface{} {
+	h.Heap.purge()
+	if n > len(h.Heap.Data) {
+		n = len(h.Heap.Data)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	var vs []reflect.Value
+	if n*h.Height() <= len(h.Heap.Data) {
+		for i := 0; i < n; i++ {
+			vs = append(vs, h.Heap.Pop(nil)[0])
+		}
+	} else {
+		take := h.Heap.smallest(n)
+		i := 0
+		vs = h.Heap.filter(func(v reflect.Value) bool {
+			i++ // filter visits every element in array order
+			return take[i-1]
+		})
+		h.Heap.popped(len(vs))
+	}
+	out := make([]interface{}, len(vs))
+	for i, v := range vs {
+		out[i] = v.Interface()
+	}
+	return out
+}
+
+// TryPop is like Pop but reports false if the heap is empty.
+func (h *GenericHeap) TryPop() (interface{}, bool) {
+	out := h.Heap.TryPop(nil)
+	return out[0].Interface(), out[1].Bool()
+}
+
+// PopOrZero is like Pop but returns the zero value of the element type,
+// rather than panicking, if the heap is empty. TryPop also reports
+// whether an element was popped.
+func (h *GenericHeap) PopOrZero() interface{} {
+	x, _ := h.TryPop()
+	return x
+}
+
+func (h *GenericHeap) Peek() (interface{}, bool) {
+	out := h.Heap.Peek(nil)
+	return out[0].Interface(), out[1].Bool()
+}
+
+// Top returns the root along with its index, for passing to Set, Fix or
+// Remove when updating the root in place. The index is -1 if the heap is
+// empty.
+func (h *GenericHeap) Top() (x interface{}, i int) {
+	x, ok := h.Peek()
+	if !ok {
+		return x, -1
+	}
+	return x, 0
+}
+
+func (h *GenericHeap) Remove(i int) interface{} {
+	return h.Heap.Remove([]reflect.Value{reflect.ValueOf(i)})[0].Interface()
+}
+
+// Insert, ExtractMin and FindMin are textbook names for Push, Pop and
+// Peek, and behave identically.
+func (h *GenericHeap) Insert(x interface{}) {
+	h.Push(x)
+}
+
+func (h *GenericHeap) ExtractMin() interface{} {
+	return h.Pop()
+}
+
+func (h *GenericHeap) FindMin() (interface{}, bool) {
+	return h.Peek()
+}
+
+func (h *GenericHeap) IsEmpty() bool {
+	return h.Heap.len() == 0
+}
+
+// Clear empties the heap but keeps the backing array for reuse.
+func (h *GenericHeap) Clear() {
+	h.Heap.NumDead = 0
+	h.Heap.truncate(0)
+}
+
+// Trim discards all but the k first elements in heap order, leaving the
+// heap holding min(k, Len()) elements. It selects them in O(Len()) and
+// does not count as popping the elements it keeps.
+func (h *GenericHeap) Trim(k int) {
+	h.Heap.purge()
+	if k >= len(h.Heap.Data) {
+		return
+	}
+	if k <= 0 {
+		h.Clear()
+		return
+	}
+	keep := h.Heap.smallest(k)
+	i := 0
+	h.Heap.filter(func(v reflect.Value) bool {
+		i++ // filter visits every element in array order
+		return !keep[i-1]
+	})
+}
+
+// Contains reports whether an element equal to x, in that neither is
+// Less than the other, is in the heap. Heaps whose user struct declares
+// a Same(a, b YourType) bool method use it instead here and in IndexOf
+// and RemoveValue.
+func (h *GenericHeap) Contains(x interface{}) bool {
+	if h.Heap.Members != nil && h.Heap.NumDead == 0 {
+		return h.Heap.Members[h.Heap.MemberKey(x)] > 0
+	}
+	return h.IndexOf(x) >= 0
+}
+
+// ContainsAll reports for each of xs whether Contains would find it, in
+// a single scan of the heap in O((n+m) log m) rather than m scans. The
+// candidates are sorted by Less, so a Same method must only report
+// elements equal that also tie under Less.
+func (h *GenericHeap) ContainsAll(xs ...interface{}) []bool {
+	out := make([]bool, len(xs))
+	if h.Heap.Members != nil && h.Heap.NumDead == 0 {
+		for i, x := range xs {
+			out[i] = h.Contains(x)
+		}
+		return out
+	}
+
+	vs := make([]reflect.Value, len(xs))
+	order := make([]int, len(xs))
+	for i, x := range xs {
+		vs[i] = h.Heap.value(x)
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return h.Heap.userLess(vs[order[a]], vs[order[b]])
+	})
+	for i, d := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		// Check the candidates that tie with d under Less.
+		j := sort.Search(len(order), func(j int) bool {
+			return !h.Heap.userLess(vs[order[j]], d)
+		})
+		for ; j < len(order) && !h.Heap.userLess(d, vs[order[j]]); j++ {
+			if h.Heap.same(d, vs[order[j]]) {
+				out[order[j]] = true
+			}
+		}
+	}
+	return out
+}
+
+// IndexOf returns the index of the first element equal to x, or -1 if
+// there is none. The index is suitable for passing to Remove or Fix.
+func (h *GenericHeap) IndexOf(x interface{}) int {
+	return h.Heap.indexOf(h.Heap.value(x))
+}
+
+// RemoveValue removes an element equal to x and reports whether one was
+// found. Among several equal elements it removes the one closest to the
+// root, or the closest one accepted by the RemoveIdentity predicate.
+func (h *GenericHeap) RemoveValue(x interface{}) bool {
+	i := h.Heap.removalIndex(h.Heap.value(x))
+	if i < 0 {
+		return false
+	}
+	if h.Heap.Lazy {
+		h.Heap.lazyRemove(i)
+	} else {
+		h.Heap.Remove([]reflect.Value{reflect.ValueOf(i)})
+	}
+	return true
+}
+
+// DecreaseKey replaces the element at index i with newVal, which must not
+// be greater than the current element per Less, and restores the ordering.
+func (h *GenericHeap) DecreaseKey(i int, newVal interface{}) error {
+	v, err := h.Heap.checkValue(newVal)
+	if err != nil {
+		return err
+	}
+	return h.Heap.changeKey(i, v, false)
+}
+
+// IncreaseKey is like DecreaseKey for a newVal that is not less than the
+// current element.
+func (h *GenericHeap) IncreaseKey(i int, newVal interface{}) error {
+	v, err := h.Heap.checkValue(newVal)
+	if err != nil {
+		return err
+	}
+	return h.Heap.changeKey(i, v, true)
+}
+
+// Reprioritize replaces the element at index i with newVal and moves it
+// up or down as needed. Unlike DecreaseKey and IncreaseKey, newVal may be
+// on either side of the current element.
+func (h *GenericHeap) Reprioritize(i int, newVal interface{}) {
+	n := h.checkIndex("Reprioritize", i)
+	v := h.Heap.value(newVal)
+	h.Heap.removed(h.Heap.Data[i])
+	h.Heap.Data[i] = h.Heap.own(v)
+	h.Heap.added(i, i+1)
+	h.Heap.down(i, n)
+	h.Heap.up(i)
+}
+
+// At returns the element at index i of Heap.Data.
+func (h *GenericHeap) At(i int) interface{} {
+	h.checkIndex("At", i)
+	return h.Heap.Data[i].Interface()
+}
+
+// Set replaces the element at index i with x and restores the ordering,
+// like Reprioritize.
+func (h *GenericHeap) Set(i int, x interface{}) {
+	h.checkIndex("Set", i)
+	h.Reprioritize(i, x)
+}
+
+// RemoveFunc removes every element for which pred returns true, with a
+// single rebuild of the heap, and returns the number removed.
+func (h *GenericHeap) RemoveFunc(pred func(x interface{}) bool) int {
+	removed := h.Heap.filter(func(v reflect.Value) bool {
+		return pred(v.Interface())
+	})
+	return len(removed)
+}
+
+// RemoveGreater removes every element x with threshold < x per Less,
+// wherever it is in the heap, and returns them in no particular order.
+// The heap is rebuilt once afterwards.
+func (h *GenericHeap) RemoveGreater(threshold interface{}) []interface{} {
+	tv := h.Heap.value(threshold)
+	removed := h.Heap.filter(func(v reflect.Value) bool {
+		return h.Heap.userLess(tv, v)
+	})
+	out := make([]interface{}, len(removed))
+	for i, v := range removed {
+		out[i] = v.Interface()
+	}
+	return out
+}
+
+// CountFunc returns the number of elements for which pred returns true,
+// without modifying the heap.
+func (h *GenericHeap) CountFunc(pred func(x interface{}) bool) int {
+	n := 0
+	for i, v := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		if pred(v.Interface()) {
+			n++
+		}
+	}
+	return n
+}
+
+// Range returns the elements x with lo <= x <= hi per Less, in heap
+// array order, without modifying the heap.
+func (h *GenericHeap) Range(lo, hi interface{}) []interface{} {
+	lv, hv := h.Heap.value(lo), h.Heap.value(hi)
+	var out []interface{}
+	for i, v := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		if !h.Heap.userLess(v, lv) && !h.Heap.userLess(hv, v) {
+			out = append(out, v.Interface())
+		}
+	}
+	return out
+}
+
+// KthSmallest returns the element at 1-based position k in heap order,
+// the k-th smallest of a min-heap, without modifying the heap. It panics
+// if k is not between 1 and Len(). Only the k first elements and their
+// children are visited, in O(k log k).
+func (h *GenericHeap) KthSmallest(k int) interface{} {
+	if k < 1 || k > h.Heap.len() {
+		panic(fmt.Sprintf("KthSmallest rank %v out of range for heap of length %v", k, h.Heap.len()))
+	}
+	// The next element in heap order is always the least candidate, and
+	// its children become candidates once it has been counted.
+	n := len(h.Heap.Data)
+	candidates := New(h.Heap.less)
+	candidates.Push(0)
+	for {
+		i := candidates.Pop()
+		for j := h.Heap.D*i + 1; j < n && j <= h.Heap.D*i+h.Heap.D; j++ {
+			candidates.Push(j)
+		}
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		if k--; k == 0 {
+			return h.Heap.Data[i].Interface()
+		}
+	}
+}
+
+// Rank returns the number of elements less than x per Less, which is the
+// position x would take in a sorted copy of the heap.
+func (h *GenericHeap) Rank(x interface{}) int {
+	v := h.Heap.value(x)
+	n := 0
+	for i, d := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		if h.Heap.userLess(d, v) {
+			n++
+		}
+	}
+	return n
+}
+
+// Min returns the smallest element per Less, or nil if the heap is empty.
+func (h *GenericHeap) Min() interface{} {
+	h.Heap.purge()
+	return h.Heap.extreme(false)
+}
+
+// Max returns the largest element per Less, or nil if the heap is empty.
+func (h *GenericHeap) Max() interface{} {
+	h.Heap.purge()
+	return h.Heap.extreme(true)
+}
+
+// Values returns a copy of the elements in heap array order.
+func (h *GenericHeap) Values() []interface{} {
+	h.Heap.purge()
+	out := make([]interface{}, len(h.Heap.Data))
+	for i, v := range h.Heap.Data {
+		out[i] = v.Interface()
+	}
+	return out
+}
+
+// ToSlice returns a copy of the elements in heap array order as a slice
+// of the element type, such as []YourType, for a single type assertion.
+func (h *GenericHeap) ToSlice() interface{} {
+	return h.Heap.slice().Interface()
+}
+
+// EachValue calls fn with each element in heap array order, stopping
+// early if fn returns false. Unlike Values it neither copies the elements
+// nor converts them to interface{}. fn must not modify the heap; doing
+// so panics once fn returns.
+func (h *GenericHeap) EachValue(fn func(v reflect.Value) bool) {
+	mods := h.Heap.Mods
+	for i, v := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		ok := fn(v)
+		h.Heap.checkMods(mods)
+		if !ok {
+			return
+		}
+	}
+}
+
+// EachIndexed calls fn with the index and value of each element in heap
+// array order, stopping early if fn returns false. The parent of the
+// element at index i > 0 is at (i-1)/d for a heap of arity d. As with
+// EachValue, fn must not modify the heap.
+func (h *GenericHeap) EachIndexed(fn func(i int, x interface{}) bool) {
+	mods := h.Heap.Mods
+	for i, v := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		ok := fn(i, v.Interface())
+		h.Heap.checkMods(mods)
+		if !ok {
+			return
+		}
+	}
+}
+
+// Merge adds the elements of other to h in O(n+m). Both heaps must hold
+// the same element type and order it the same way; other is unchanged.
+func (h *GenericHeap) Merge(other *GenericHeap) {
+	if h.Heap.ElemType != other.Heap.ElemType || h.Heap.Max != other.Heap.Max {
+		panic(fmt.Sprintf("cannot merge heap of %v into heap of %v with a different ordering", other.Heap.ElemType, h.Heap.ElemType))
+	}
+	other.Heap.purge()
+	h.Heap.pushAll(other.Heap.Data)
+}
+
+// MergeSlice is like Merge for the elements of a plain slice of the
+// element type, and is the same as PushSlice.
+func (h *GenericHeap) MergeSlice(slice interface{}) {
+	h.PushSlice(slice)
+}
+
+// PushBatch pushes all of xs with a single O(n+m) rebuild of the heap.
+func (h *GenericHeap) PushBatch(xs ...interface{}) {
+	vs := make([]reflect.Value, len(xs))
+	for i, x := range xs {
+		vs[i] = h.Heap.value(x)
+	}
+	h.Heap.pushAll(vs)
+}
+
+// PushSlice pushes the elements of slice, which must be a slice of the
+// element type, with a single rebuild of the heap. A nil or empty slice
+// leaves the heap unchanged, as does calling PushBatch with no elements.
+func (h *GenericHeap) PushSlice(slice interface{}) {
+	h.Heap.pushAll(h.Heap.values(reflect.ValueOf(slice)))
+}
+
+// SwapContents replaces the elements of the heap with those of slice,
+// which must be a slice of the element type, in a single O(n) rebuild,
+// and returns the old elements as a slice of the element type in heap
+// array order. slice is copied, so the caller keeps ownership of it.
+func (h *GenericHeap) SwapContents(slice interface{}) interface{} {
+	return h.Heap.swapContents(h.Heap.values(reflect.ValueOf(slice))).Interface()
+}
+
+// AppendUnsorted adds x to the end of Heap.Data without restoring the
+// heap ordering. The heap is invalid until Heapify is called, and no
+// other method may be used before then. It panics on bounded heaps.
+func (h *GenericHeap) AppendUnsorted(x interface{}) {
+	if h.Heap.Bound > 0 {
+		panic("AppendUnsorted called on a bounded heap")
+	}
+	v := h.Heap.value(x)
+	if h.Heap.merges(v) {
+		h.Heap.push(v)
+		return
+	}
+	if err := h.Heap.checkRoom(1); err != nil {
+		panic(err.Error())
+	}
+	if h.Heap.Counters != nil {
+		h.Heap.Counters.Pushes++
+	}
+	h.Heap.Data = append(h.Heap.Data, h.Heap.own(v))
+	h.Heap.added(len(h.Heap.Data)-1, len(h.Heap.Data))
+}
+
+// MapInPlace replaces each element x with fn(x) and then rebuilds the
+// heap once in O(n). On stable heaps the new elements keep the insertion
+// order of the ones they replace.
+func (h *GenericHeap) MapInPlace(fn func(x interface{}) interface{}) {
+	for i, v := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		nv := h.Heap.own(h.Heap.value(fn(v.Interface())))
+		h.Heap.removed(v)
+		h.Heap.Data[i] = nv
+		h.Heap.refreshKey(i)
+		if h.Heap.SetIndex != nil {
+			h.Heap.SetIndex(nv.Interface(), i)
+		}
+		if h.Heap.Members != nil {
+			h.Heap.Members[h.Heap.MemberKey(nv.Interface())]++
+		}
+	}
+	h.Heap.heapify()
+}
+
+// LoadSorted replaces the contents of the heap with the elements of
+// slice, which must already be sorted in heap order, ascending per Less
+// for a min-heap. A sorted slice is a valid heap, so unlike PushSlice no
+// rebuild is needed. Heaps with the Debug option panic if slice is not
+// in order; others are left invalid. Bounded heaps and heaps with
+// NoDuplicates or Coalesce may drop or merge elements, so they push the
+// elements one by one instead, and LoadSorted panics without changing the
+// heap if slice holds more than MaxSize elements.
+func (h *GenericHeap) LoadSorted(slice interface{}) {
+	vs := h.Heap.values(reflect.ValueOf(slice))
+	if h.Heap.Bound > 0 || h.Heap.Combine != nil || h.Heap.NoDups {
+		h.Clear()
+		h.Heap.pushAll(vs)
+		return
+	}
+	if h.Heap.MaxSize > 0 && len(vs) > h.Heap.MaxSize {
+		panic(fmt.Sprintf("%v: cannot load %v elements with MaxSize %v", ErrFull, len(vs), h.Heap.MaxSize))
+	}
+	h.Clear()
+	if h.Heap.Counters != nil {
+		h.Heap.Counters.Pushes += len(vs)
+	}
+	for _, v := range vs {
+		h.Heap.Data = append(h.Heap.Data, h.Heap.own(v))
+	}
+	h.Heap.added(0, len(h.Heap.Data))
+	if h.Heap.Debug {
+		if err := h.Heap.validate(); err != nil {
+			panic("LoadSorted: " + err.Error())
+		}
+	}
+}
+
+// Heapify rebuilds the heap in O(n) after any number of elements of
+// Heap.Data were changed directly.
+func (h *GenericHeap) Heapify() {
+	if h.Heap.Members != nil {
+		// The key counts can't be trusted either way, so recount them as
+		// load does.
+		h.Heap.Members = make(map[interface{}]int)
+	}
+	if h.Heap.tracksMeta() && len(h.Heap.Meta) != len(h.Heap.Data) {
+		// Elements were added or dropped behind our back, so their
+		// sequence numbers and tombstones can't be trusted.
+		h.Heap.Meta = nil
+		h.Heap.NumDead = 0
+		h.Heap.added(0, len(h.Heap.Data))
+	} else if h.Heap.Members != nil {
+		for _, v := range h.Heap.Data {
+			h.Heap.Members[h.Heap.MemberKey(v.Interface())]++
+		}
+	}
+	for i := range h.Heap.Meta {
+		h.Heap.refreshKey(i)
+	}
+	h.Heap.updateVars()
+	h.Heap.heapify()
+}
+
+// SetLess replaces the comparator of the heap and rebuilds it under the
+// new ordering. less may be a func(a, b interface{}) bool, as taken by
+// NewFunc, or a typed comparator for the element type, as taken by Define.
+// It replaces the key of a heap built by NewKeyFunc.
+func (h *GenericHeap) SetLess(less interface{}) {
+	impl := h.Heap
+	impl.LessCtx = nil
+	if f, ok := less.(func(a, b interface{}) bool); ok {
+		impl.LessFunc = f
+		impl.LessFast = nil
+	} else {
+		lessImpl := reflect.ValueOf(less)
+		if lessImpl.Kind() != reflect.Func {
+			panic(fmt.Sprintf("expected a comparator func, got %T", less))
+		}
+		if err := checkLess(lessImpl.Type()); err != nil {
+			panic(err.Error())
+		}
+		if lessImpl.Type().In(0) != impl.ElemType {
+			panic(fmt.Sprintf("comparator %v does not compare %v", lessImpl.Type(), impl.ElemType))
+		}
+		impl.setLessImpl(lessImpl)
+		impl.LessFunc = nil
+	}
+	if impl.KeyFunc != nil {
+		impl.KeyFunc = nil
+		if !impl.tracksMeta() {
+			impl.Meta = nil
+		}
+	}
+	impl.heapify()
+}
+
+// Rearity changes the number of children per node to d, as set by the
+// Arity option, and rebuilds the heap in O(n) under the new layout.
+func (h *GenericHeap) Rearity(d int) {
+	if d < 2 {
+		panic(fmt.Sprintf("invalid arity %v: must be at least 2", d))
+	}
+	if d == h.Heap.D {
+		return
+	}
+	h.Heap.D = d
+	h.Heap.heapify()
+}
+
+// SetContext replaces the context passed to the comparator of a heap
+// built by NewContextFunc and rebuilds the heap in O(n), since elements
+// ordered under the old context need not be ordered under the new one.
+func (h *GenericHeap) SetContext(ctx interface{}) {
+	if h.Heap.LessCtx == nil {
+		panic("SetContext called on a heap without a context comparator")
+	}
+	h.Heap.Ctx = ctx
+	h.Heap.heapify()
+}
+
+// ValidUnder reports whether the heap ordering holds with less in place
+// of the heap's own comparator, which is reversed as usual for max-heaps.
+// less may be of any form accepted by SetLess.
+func (h *GenericHeap) ValidUnder(less interface{}) bool {
+	lessValues := comparator(less, h.Heap.ElemType)
+	for j := 1; j < len(h.Heap.Data); j++ {
+		a, b := h.Heap.Data[j], h.Heap.Data[(j-1)/h.Heap.D]
+		if h.Heap.Max {
+			a, b = b, a
+		}
+		if lessValues(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that no element is less than its parent, returning an
+// error describing the first violation found.
+func (h *GenericHeap) Validate() error {
+	return h.Heap.validate()
+}
+
+// maxStringElems caps the number of elements String prints.
+const maxStringElems = 20
+
+func (h *GenericHeap) String() string {
+	h.Heap.purge()
+	data := h.Heap.Data
+	if len(data) == 0 {
+		return "Heap[len=0]"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Heap[len=%v, top=%v, data=[", len(data), data[0])
+	for i, v := range data {
+		if i == maxStringElems {
+			b.WriteString(" ...")
+			break
+		}
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprint(&b, v)
+	}
+	b.WriteString("]]")
+	return b.String()
+}
+
+// Tree renders the heap as an indented tree, one element per line with
+// its children below it indented by two more spaces.
+func (h *GenericHeap) Tree() string {
+	h.Heap.purge()
+	var b strings.Builder
+	h.Heap.writeTree(&b, 0, 0)
+	return b.String()
+}
+
+// Iterate calls fn for each element in heap order, stopping early if fn
+// returns false. It works on a copy, so the heap itself is unchanged.
+func (h *GenericHeap) Iterate(fn func(x interface{}) bool) {
+	c := h.Heap.clone()
+	for c.len() > 0 {
+		if !fn(c.Pop(nil)[0].Interface()) {
+			return
+		}
+	}
+}
+
+// A Cursor yields the elements of a heap in heap order on demand, by
+// popping from a copy taken when it was created.
+type Cursor struct {
+	h *heap
+}
+
+// Cursor returns a Cursor over the current elements. The heap itself is
+// unchanged, and later changes to it are not seen by the Cursor. Each
+// call to Next costs one pop, so a caller that stops early pays only for
+// the elements it consumed beyond the O(n) copy.
+func (h *GenericHeap) Cursor() *Cursor {
+	return &Cursor{h: h.Heap.clone()}
+}
+
+// Next returns the next element in heap order, or false once every
+// element has been returned.
+func (c *Cursor) Next() (interface{}, bool) {
+	if c.h.len() == 0 {
+		return nil, false
+	}
+	return c.h.Pop(nil)[0].Interface(), true
+}
+
+// Drain pops every element in heap order, passing each to fn, and leaves
+// the heap empty.
+func (h *GenericHeap) Drain(fn func(x interface{})) {
+	for h.Heap.len() > 0 {
+		fn(h.Heap.Pop(nil)[0].Interface())
+	}
+}
+
+// DrainBatches pops every element in heap order and passes them to fn in
+// slices of batchSize, the last of which may be shorter, leaving the
+// heap empty. Each batch is a new slice that fn may keep.
+func (h *GenericHeap) DrainBatches(batchSize int, fn func(batch []interface{})) {
+	if batchSize < 1 {
+		panic(fmt.Sprintf("invalid batch size %v: must be positive", batchSize))
+	}
+	for h.Heap.len() > 0 {
+		n := batchSize
+		if l := h.Heap.len(); l < n {
+			n = l
+		}
+		batch := make([]interface{}, n)
+		for i := range batch {
+			batch[i] = h.Heap.Pop(nil)[0].Interface()
+		}
+		fn(batch)
+	}
+}
+
+// PopWhile pops elements in heap order for as long as pred returns true
+// for the root, and returns them in the order they were popped.
+func (h *GenericHeap) PopWhile(pred func(x interface{}) bool) []interface{} {
+	var out []interface{}
+	for {
+		x, ok := h.Peek()
+		if !ok || !pred(x) {
+			return out
+		}
+		out = append(out, h.Pop())
+	}
+}
+
+// Stats returns the operation counters of a heap initialized with
+// CollectStats. Only Len is set for other heaps.
+func (h *GenericHeap) Stats() Stats {
+	var s Stats
+	if h.Heap.Counters != nil {
+		s = *h.Heap.Counters
+	}
+	s.Len = h.Heap.len()
+	return s
+}
+
+// LastCompareCount returns the number of comparisons made by the most
+// recent Push or Pop, counting those of any other operations since then,
+// on heaps initialized with CollectStats. It is 0 for other heaps.
+func (h *GenericHeap) LastCompareCount() int {
+	return h.Heap.LastCompares
+}
+
+// Equal reports whether h and other hold the same elements, compared
+// with h's Less, regardless of how they are arranged internally.
+func (h *GenericHeap) Equal(other *GenericHeap) bool {
+	h.Heap.purge()
+	other.Heap.purge()
+	if h.Heap.ElemType != other.Heap.ElemType || len(h.Heap.Data) != len(other.Heap.Data) {
+		return false
+	}
+	a := h.Heap.sorted(h.Heap.Data)
+	b := h.Heap.sorted(other.Heap.Data)
+	for i := range a {
+		if !h.Heap.equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Height returns the number of levels in the heap tree, which bounds the
+// number of moves made by the next Push or Pop. It is 0 for an empty heap.
+func (h *GenericHeap) Height() int {
+	height := 0
+	for i := len(h.Heap.Data) - 1; i >= 0; i = (i - 1) / h.Heap.D {
+		height++
+		if i == 0 {
+			break
+		}
+	}
+	return height
+}
+
+// ElemType returns the type of the elements held by the heap.
+func (h *GenericHeap) ElemType() reflect.Type {
+	return h.Heap.ElemType
+}
+
+// Bound returns the capacity of a bounded heap, or 0 if it is unbounded.
+func (h *GenericHeap) Bound() int {
+	return h.Heap.Bound
+}
+
+// Clone returns a copy of the heap that can be modified independently of
+// the original. Elements are copied shallowly, so pointer elements are
+// shared between the two heaps. Hooks such as TrackIndex and OnSwap are not
+// carried over to the copy.
+func (h *GenericHeap) Clone() *GenericHeap {
+	h.Heap.purge()
+	return &GenericHeap{Heap: h.Heap.clone()}
+}
+
+// Split returns two new heaps with the same configuration as h, kept
+// holding the elements for which pred returns true and shed the others.
+// Each is built once in O(n), and h itself is left unchanged.
+func (h *GenericHeap) Split(pred func(x interface{}) bool) (kept, shed *GenericHeap) {
+	var kv, sv []reflect.Value
+	for i, v := range h.Heap.Data {
+		if h.Heap.NumDead > 0 && h.Heap.Meta[i].dead {
+			continue
+		}
+		if pred(v.Interface()) {
+			kv = append(kv, v)
+		} else {
+			sv = append(sv, v)
+		}
+	}
+	kept = &GenericHeap{Heap: h.Heap.emptyClone()}
+	shed = &GenericHeap{Heap: h.Heap.emptyClone()}
+	kept.Heap.pushAll(kv)
+	shed.Heap.pushAll(sv)
+	return kept, shed
+}
+
+// Reserve grows the backing array so it can hold at least n elements
+// without reallocating.
+func (h *GenericHeap) Reserve(n int) {
+	if n <= cap(h.Heap.Data) {
+		return
+	}
+	data := make([]reflect.Value, len(h.Heap.Data), n)
+	copy(data, h.Heap.Data)
+	h.Heap.Data = data
+}
+
+// Cap returns the capacity of the backing array, for deciding when a
+// Reserve or Compact is worthwhile.
+func (h *GenericHeap) Cap() int {
+	return cap(h.Heap.Data)
+}
+
+// Compact shrinks the backing array to fit the current elements, so a
+// large array left over from earlier growth can be garbage collected.
+func (h *GenericHeap) Compact() {
+	h.Heap.purge()
+	h.Heap.compact()
+}
+
+// Fix re-establishes the heap ordering after the element at index i has
+// changed its value.
+func (h *GenericHeap) Fix(i int) {
+	n := h.checkIndex("Fix", i)
+	h.Heap.refreshKey(i)
+	h.Heap.down(i, n)
+	h.Heap.up(i)
+}
+
+// FixAll re-establishes the heap ordering after the elements at the
+// given indices have changed their values. It restores only the changed
+// elements and their ancestors, or rebuilds the whole heap in O(n) when so
+// many have changed that this would be cheaper.
+func (h *GenericHeap) FixAll(indices ...int) {
+	n := len(h.Heap.Data)
+	for _, i := range indices {
+		h.checkIndex("FixAll", i)
+		h.Heap.refreshKey(i)
+	}
+	if len(indices)*h.Height() > n {
+		h.Heap.heapify()
+		return
+	}
+
+	// Like heapify, but skipping subtrees without changed elements, which
+	// are already ordered.
+	seen := make(map[int]bool)
+	var nodes []int
+	for _, i := range indices {
+		for !seen[i] {
+			seen[i] = true
+			nodes = append(nodes, i)
+			i = (i - 1) / h.Heap.D
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(nodes)))
+	for _, i := range nodes {
+		h.Heap.down(i, n)
+	}
+}
+
+// SiftUp moves the element at index i towards the root until its parent
+// is not greater than it. The rest of the heap must already be ordered.
+func (h *GenericHeap) SiftUp(i int) {
+	h.checkIndex("SiftUp", i)
+	h.Heap.refreshKey(i)
+	h.Heap.up(i)
+}
+
+// SiftDown moves the element at index i towards the leaves until none of
+// its children is less than it. The subtrees below i must already be
+// ordered.
+func (h *GenericHeap) SiftDown(i int) {
+	n := h.checkIndex("SiftDown", i)
+	h.Heap.refreshKey(i)
+	h.Heap.down(i, n)
+}
+
+// Parent returns the index of the parent of the element at index i, or
+// -1 for the root or an index out of range.
+func (h *GenericHeap) Parent(i int) int {
+	if i <= 0 || i >= len(h.Heap.Data) {
+		return -1
+	}
+	return (i - 1) / h.Heap.D
+}
+
+// Children returns the indices of the children of the element at index
+// i, which are empty for a leaf or an index out of range.
+func (h *GenericHeap) Children(i int) []int {
+	if i < 0 || i >= h.Heap.firstLeaf() {
+		return nil
+	}
+	var out []int
+	first := h.Heap.D*i + 1
+	for j := first; j-first < h.Heap.D && j < len(h.Heap.Data); j++ {
+		out = append(out, j)
+	}
+	return out
+}
+
+// checkIndex panics if i is not a valid index for op or refers to a
+// lazily deleted element, and otherwise returns the length of Heap.Data.
+func (h *GenericHeap) checkIndex(op string, i int) int {
+	n := len(h.Heap.Data)
+	if i < 0 || i >= n {
+		panic(fmt.Sprintf("%v index %v out of range for heap of length %v", op, i, n))
+	}
+	if h.Heap.isDead(i) {
+		panic(fmt.Sprintf("%v index %v refers to a deleted element", op, i))
+	}
+	return n
+}
+
+func getGenericFunc(l reflect.Value, name string)  (func([]reflect.Value) []reflect.Value) {
+	return l.MethodByName(name).Interface().(func([]reflect.Value) []reflect.Value)
+}
+
+// An Option configures the private heap during Init.
+type Option func(*heap) error
+
+// MaxHeap orders the heap so the largest element per Less is at the root.
+func MaxHeap() Option {
+	return func(h *heap) error {
+		h.Max = true
+		return nil
+	}
+}
+
+// Arity sets the number of children per node, which defaults to 2.
+func Arity(d int) Option {
+	return func(h *heap) error {
+		if d < 2 {
+			return fmt.Errorf("invalid arity %v: must be at least 2", d)
+		}
+		h.D = d
+		return nil
+	}
+}
+
+// Bounded limits the heap to k elements. Once full, a pushed element
+// replaces the root only if the root is less than it, so a bounded
+// min-heap keeps the k largest elements pushed.
+func Bounded(k int) Option {
+	return func(h *heap) error {
+		if k < 1 {
+			return fmt.Errorf("invalid bound %v: must be at least 1", k)
+		}
+		h.Bound = k
+		return nil
+	}
+}
+
+// OnEvict calls fn with each element a full bounded heap drops, which is
+// either the root displaced by a pushed element or the pushed element
+// itself when it does not beat the root.
+func OnEvict(fn func(x interface{})) Option {
+	return func(h *heap) error {
+		h.OnEvict = fn
+		return nil
+	}
+}
+
+// MaxSize limits the heap to n elements. Pushing beyond that panics with
+// a "heap full" message, or returns an error from PushE and Push fields
+// declared as func(YourType) error. Unlike Bounded, nothing is evicted.
+func MaxSize(n int) Option {
+	return func(h *heap) error {
+		if n < 1 {
+			return fmt.Errorf("invalid max size %v: must be at least 1", n)
+		}
+		h.MaxSize = n
+		return nil
+	}
+}
+
+// Capacity preallocates room for n elements, so the heap does not
+// reallocate its backing array until it grows beyond n.
+func Capacity(n int) Option {
+	return func(h *heap) error {
+		if n < 0 {
+			return fmt.Errorf("invalid capacity %v: must not be negative", n)
+		}
+		h.Data = make([]reflect.Value, 0, n)
+		return nil
+	}
+}
+
+// AutoShrink makes the heap reallocate its backing array at twice its
+// length whenever removing elements leaves it less than 1/factor full,
+// so memory is reclaimed after large drains. factor must be at least 3.
+func AutoShrink(factor int) Option {
+	return func(h *heap) error {
+		if factor < 3 {
+			return fmt.Errorf("invalid shrink factor %v: must be at least 3", factor)
+		}
+		h.ShrinkFactor = factor
+		return nil
+	}
+}
+
+// A NilPolicy decides how a heap with a nilable element type treats nil
+// elements.
+type NilPolicy int
+
+const (
+	NilsToLess NilPolicy = iota // pass nils to Less like other elements
+	NilsReject // panic on pushing nil, or return ErrNil from PushE
+	NilsFirst // order nils before all other elements
+	NilsLast // order nils after all other elements
+)
+
+// Nils sets the policy for nil elements, which defaults to NilsToLess.
+// NilsFirst and NilsLast order nils per Less, so they are reversed on
+// max-heaps, and never pass nils to Less or to the key function.
+func Nils(policy NilPolicy) Option {
+	return func(h *heap) error {
+		if policy < NilsToLess || policy > NilsLast {
+			return fmt.Errorf("invalid nil policy %v", policy)
+		}
+		h.Nils = policy
+		return nil
+	}
+}
+
+// CopyOnPush makes the heap store its own copy of each pushed element, so
+// later changes to the caller's value don't affect the heap ordering. For
+// pointer elements the pointed-to value is copied, costing an allocation
+// per push. Copies are shallow.
+func CopyOnPush() Option {
+	return func(h *heap) error {
+		h.CopyOnPush = true
+		return nil
+	}
+}
+
+// Debug enables consistency checks that are too costly for normal use,
+// such as validating the input to LoadSorted, and makes the heap panic if
+// Less is found not to be a strict weak order by CheckLess, run on every
+// rebuild and every 1024 pushes.
+func Debug() Option {
+	return func(h *heap) error {
+		h.Debug = true
+		return nil
+	}
+}
+
+// Stats holds the counters collected by heaps initialized with
+// CollectStats.
+type Stats struct {
+	Pushes int
+	Pops int
+	Swaps int
+	Len int
+	PeakLen int
+}
+
+// CollectStats makes the heap count its operations, see GenericHeap.Stats.
+func CollectStats() Option {
+	return func(h *heap) error {
+		h.Counters = new(Stats)
+		return nil
+	}
+}
+
+// Stable makes equal elements leave the heap in the order they were
+// pushed, at the cost of an extra comparison when elements tie.
+func Stable() Option {
+	return func(h *heap) error {
+		h.Stable = true
+		return nil
+	}
+}
+
+// Coalesce merges a pushed element into an element already in the heap
+// for which sameKey returns true, replacing it with the result of combine
+// instead of storing a duplicate. Like NoDuplicates it applies to every
+// method that adds elements. Pushes scan the heap in O(n) to find a
+// match.
+func Coalesce(sameKey func(a, b interface{}) bool, combine func(existing, incoming interface{}) interface{}) Option {
+	return func(h *heap) error {
+		h.SameKey = sameKey
+		h.Combine = combine
+		return nil
+	}
+}
+
+// NoDuplicates makes pushing an element equal to one already in the heap
+// a no-op, using the same equality as Contains. It applies to every
+// method that adds elements, including Replace, PushPop and
+// AppendUnsorted. Pushes scan the heap in O(n) to find a match.
+func NoDuplicates() Option {
+	return func(h *heap) error {
+		h.NoDups = true
+		return nil
+	}
+}
+
+// RemoveIdentity makes RemoveValue pick among the elements equal to x
+// only one for which identity(elem, x) returns true, such as the element
+// with the same ID, instead of whichever is closest to the root.
+func RemoveIdentity(identity func(elem, x interface{}) bool) Option {
+	return func(h *heap) error {
+		h.Identity = identity
+		return nil
+	}
+}
+
+// Fair serves elements that tie under Less round-robin when they are
+// pushed back after being popped, as in a scheduler: a re-pushed element
+// goes behind the others it ties with rather than ahead of them. This is
+// the insertion order guarantee of Stable, which Fair enables.
+func Fair() Option {
+	return Stable()
+}
+
+// TieBreak orders elements that Less reports as equal by tieBreak. When
+// the two together form a total order, elements are popped in an order
+// that depends only on the contents of the heap and not on the order
+// they were pushed in. It takes precedence over Stable.
+func TieBreak(tieBreak func(a, b interface{}) bool) Option {
+	return func(h *heap) error {
+		h.TieBreak = tieBreak
+		return nil
+	}
+}
+
+// LazyDelete makes RemoveValue mark elements as deleted instead of
+// removing them immediately. Deleted elements are skipped when they reach
+// the root and purged once they outnumber the live ones, or before
+// operations that look at every element.
+//
+// The heap keeps its elements alive, as Less needs them to keep the heap
+// ordered. A WeakHeap holds pointer elements that may be garbage
+// collected instead.
+func LazyDelete() Option {
+	return func(h *heap) error {
+		h.Lazy = true
+		return nil
+	}
+}
+
+// TrackIndex calls setIndex with an element and its new index whenever
+// the element moves, and with -1 when it leaves the heap, so elements
+// can record their own position for use with Fix or Remove.
+func TrackIndex(setIndex func(x interface{}, i int)) Option {
+	return func(h *heap) error {
+		h.SetIndex = setIndex
+		return nil
+	}
+}
+
+// MembershipIndex keeps a count of the elements under each key returned
+// by key, which must be comparable and equal exactly for elements that
+// Contains considers equal, such as an ID field. Contains then runs in
+// O(1), and IndexOf, RemoveValue and NoDuplicates skip their O(n) scan
+// when no element matches. The index costs a map entry per distinct key
+// and a call to key plus a map update for every element pushed or
+// removed.
+func MembershipIndex(key func(x interface{}) interface{}) Option {
+	return func(h *heap) error {
+		h.MemberKey = key
+		h.Members = make(map[interface{}]int)
+		return nil
+	}
+}
+
+// OnSwap calls fn with the indices of every pair of elements the heap
+// exchanges while restoring its ordering.
+func OnSwap(fn func(i, j int)) Option {
+	return func(h *heap) error {
+		h.OnSwap = fn
+		return nil
+	}
+}
+
+// OnPush calls fn with the time taken by each push.
+func OnPush(fn func(d time.Duration)) Option {
+	return func(h *heap) error {
+		h.OnPush = fn
+		return nil
+	}
+}
+
+// OnPop calls fn with the time taken by each pop.
+func OnPop(fn func(d time.Duration)) Option {
+	return func(h *heap) error {
+		h.OnPop = fn
+		return nil
+	}
+}
+
+// Optional fields are wired by Init when the user struct declares them:
+//
+//	Peek   func() (YourType, bool)  // the root, if any
+//	PopAll func() []YourType        // drains the heap in order
+//	TopK   func(k int) []YourType   // the k first elements in order
+var optionalFields = []string{"Peek", "PopAll", "TopK"}
+
+// isSameFunc reports whether eq has the signature func(a, b elemType)
+// bool of an optional Same method, which Init uses instead of Less to
+// identify elements in Contains, IndexOf and RemoveValue. It is not
+// called Equal so as not to clash with GenericHeap.Equal.
+func isSameFunc(eq reflect.Type, elemType reflect.Type) bool {
+	return eq.NumIn() == 2 && eq.In(0) == elemType && eq.In(1) == elemType &&
+		eq.NumOut() == 1 && eq.Out(0).Kind() == reflect.Bool
+}
+
+func newHeap(elemType reflect.Type, opts []Option) (*heap, error) {
+	impl := new(heap)
+	impl.ElemType = elemType
+	impl.D = 2
+	for _, opt := range opts {
+		if err := opt(impl); err != nil {
+			return nil, err
+		}
+	}
+	if impl.Vars != nil {
+		impl.publishVars()
+	}
+	return impl, nil
+}
+
+// Init wires the Heap field and func fields of h, a pointer to a struct
+// embedding GenericHeap, and returns a GenericHeap sharing its state for
+// access to operations the struct does not expose. It panics if h is
+// already initialized.
+func Init(h interface{}, opts ...Option) *GenericHeap {
+	if err := InitE(h, opts...); err != nil {
+		panic(err.Error())
+	}
+	impl := reflect.ValueOf(h).Elem().FieldByName("Heap").Interface().(*heap)
+	return &GenericHeap{Heap: impl}
+}
+
+// InitE is like Init but returns an error instead of panicking when h
+// does not have the expected fields and methods.
+func InitE(h interface{}, opts ...Option) error {
+	ptr := reflect.ValueOf(h)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: expected a pointer to a struct, got %T", ErrTypeMismatch, h)
+	}
+	obj := ptr.Elem()
+
+	heapField := obj.FieldByName("Heap")
+	if !heapField.IsValid() || heapField.Type() != reflect.TypeOf((*heap)(nil)) {
+		return fmt.Errorf("%w: expected %v to embed GenericHeap", ErrMissingMethod, obj.Type())
+	}
+	if !heapField.IsNil() {
+		return fmt.Errorf("%w: %v", ErrInitialized, obj.Type())
+	}
+
+	lessImpl := ptr.MethodByName("Less")
+	if !lessImpl.IsValid() {
+		return fmt.Errorf("%w Less: expected implementation of func (*YourHeap) Less(a, b YourType) bool", ErrMissingMethod)
+	}
+	if err := checkLess(lessImpl.Type()); err != nil {
+		return fmt.Errorf("%w Less: %v", ErrMissingMethod, err)
+	}
+
+	fields := make(map[string]reflect.Value)
+	for _, fieldName := range []string{"Push", "Pop", "Remove"} {
+		orig := obj.FieldByName(fieldName)
+		if !orig.IsValid() || orig.Kind() != reflect.Func {
+			return fmt.Errorf("%w %v: expected a func field %v on %v", ErrMissingMethod, fieldName, fieldName, obj.Type())
+		}
+		fields[fieldName] = orig
+	}
+	for _, fieldName := range optionalFields {
+		orig := obj.FieldByName(fieldName)
+		if orig.IsValid() && orig.Kind() == reflect.Func {
+			fields[fieldName] = orig
+		}
+	}
+
+	impl, err := newHeap(lessImpl.Type().In(0), opts)
+	if err != nil {
+		return err
+	}
+	implValue := reflect.ValueOf(impl)
+	impl.setLessImpl(directLess(h, lessImpl))
+	if eq := ptr.MethodByName("Same"); eq.IsValid() && isSameFunc(eq.Type(), impl.ElemType) {
+		impl.SameImpl = eq
+	}
+
+	heapField.Set(implValue)
+	for fieldName, orig := range fields {
+		implName := fieldName
+		if fieldName == "Pop" && orig.Type().NumOut() == 2 {
+			implName = "TryPop"
+		}
+		if fieldName == "Push" && orig.Type().NumOut() == 1 {
+			implName = "PushE"
+		}
+		generic := getGenericFunc(implValue, implName)
+		orig.Set(reflect.MakeFunc(orig.Type(), generic))
+	}
+	return nil
+}
+
+// IsInitialized reports whether h, a pointer to a struct embedding
+// GenericHeap, has been set up by Init: its Heap field is set, its Push,
+// Pop and Remove fields are wired and it has a Less method.
+func IsInitialized(h interface{}) bool {
+	ptr := reflect.ValueOf(h)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	obj := ptr.Elem()
+
+	heapField := obj.FieldByName("Heap")
+	if !heapField.IsValid() || heapField.Type() != reflect.TypeOf((*heap)(nil)) || heapField.IsNil() {
+		return false
+	}
+	if !ptr.MethodByName("Less").IsValid() {
+		return false
+	}
+	for _, fieldName := range []string{"Push", "Pop", "Remove"} {
+		f := obj.FieldByName(fieldName)
+		if !f.IsValid() || f.Kind() != reflect.Func || f.IsNil() {
+			return false
+		}
+	}
+	return true
+}
+
+func InitMax(h interface{}) {
+	Init(h, MaxHeap())
+}
+
+func InitD(h interface{}, d int) {
+	Init(h, Arity(d))
+}
+
+func InitBounded(h interface{}, k int) {
+	Init(h, Bounded(k))
+}
+
+func InitStable(h interface{}) {
+	Init(h, Stable())
+}
+
+// Heapify initializes h like Init and fills it from slice in O(n), as
+// PushSlice does, so slice must be a slice of the element type. The
+// elements are copied, so the caller keeps ownership of slice, which may
+// be nil.
+func Heapify(h interface{}, slice interface{}, opts ...Option) {
+	Init(h, opts...)
+
+	impl := reflect.ValueOf(h).Elem().FieldByName("Heap").Interface().(*heap)
+	impl.pushAll(impl.values(reflect.ValueOf(slice)))
+}
+
+// NewFunc returns a heap of elemType ordered by less, without the need
+// for a user struct. Use the untyped Push and Pop methods to access it.
+func NewFunc(elemType reflect.Type, less func(a, b interface{}) bool, opts ...Option) *GenericHeap {
+	impl, err := newHeap(elemType, opts)
+	if err != nil {
+		panic(err.Error())
+	}
+	impl.LessFunc = less
+	return &GenericHeap{Heap: impl}
+}
+
+// NewContextFunc is like NewFunc for a comparator that also depends on
+// external state, such as the current time or a table of weights, which
+// is passed to it as ctx. The heap ordering only holds for a fixed ctx:
+// change it with SetContext, which rebuilds the heap, rather than by
+// modifying what ctx refers to.
+func NewContextFunc(elemType reflect.Type, less func(ctx, a, b interface{}) bool, ctx interface{}, opts ...Option) *GenericHeap {
+	impl, err := newHeap(elemType, opts)
+	if err != nil {
+		panic(err.Error())
+	}
+	impl.LessCtx = less
+	impl.Ctx = ctx
+	return &GenericHeap{Heap: impl}
+}
+
+// Define returns a heap of elemType ordered by less, a typed comparator
+// of the form func(a, b elemType) bool or int, without the need for a
+// user struct.
+func Define(elemType reflect.Type, less interface{}, opts ...Option) *GenericHeap {
+	lessImpl := reflect.ValueOf(less)
+	if lessImpl.Kind() != reflect.Func {
+		panic(fmt.Sprintf("expected a comparator func, got %T", less))
+	}
+	if err := checkLess(lessImpl.Type()); err != nil {
+		panic(err.Error())
+	}
+	if lessImpl.Type().In(0) != elemType {
+		panic(fmt.Sprintf("comparator %v does not compare %v", lessImpl.Type(), elemType))
+	}
+
+	impl, err := newHeap(elemType, opts)
+	if err != nil {
+		panic(err.Error())
+	}
+	impl.setLessImpl(lessImpl)
+	return &GenericHeap{Heap: impl}
+}
+
+// NewKeyFunc returns a heap of elemType ordered by the number key returns
+// for each element. Keys are computed once per element and cached, so an
+// element changed in place must be passed to Fix to update its key.
+func NewKeyFunc(elemType reflect.Type, key func(x interface{}) float64, opts ...Option) *GenericHeap {
+	h := NewFunc(elemType, func(a, b interface{}) bool {
+		return key(a) < key(b)
+	}, opts...)
+	h.Heap.KeyFunc = key
+	return h
 }
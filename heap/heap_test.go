@@ -0,0 +1,2742 @@
+package heap
+
+import "errors"
+import "fmt"
+import "math"
+import "math/rand"
+import "reflect"
+import "sort"
+import "strings"
+import "testing"
+import "time"
+
+type IntHeap struct {
+	GenericHeap
+	Push   func(int)
+	Pop    func() int
+	Remove func(int) int
+	Peek   func() (int, bool)
+	PopAll func() []int
+	TopK   func(int) []int
+}
+
+func (h *IntHeap) Less(a, b int) bool { return a < b }
+
+// OkHeap declares Pop in the form that reports whether the heap was empty.
+type OkHeap struct {
+	GenericHeap
+	Push   func(int)
+	Pop    func() (int, bool)
+	Remove func(int) int
+}
+
+func (h *OkHeap) Less(a, b int) bool { return a < b }
+
+// CmpHeap orders strings by a three-way comparison.
+type CmpHeap struct {
+	GenericHeap
+	Push   func(string)
+	Pop    func() string
+	Remove func(int) string
+}
+
+func (h *CmpHeap) Less(a, b string) int { return strings.Compare(a, b) }
+
+type badLessHeap struct {
+	GenericHeap
+	Push   func(string)
+	Pop    func() string
+	Remove func(int) string
+}
+
+func (h *badLessHeap) Less(a, b string) string { return "" }
+
+// ErrHeap declares Push in the form that returns ErrFull.
+type ErrHeap struct {
+	GenericHeap
+	Push   func(int) error
+	Pop    func() int
+	Remove func(int) int
+}
+
+func (h *ErrHeap) Less(a, b int) bool { return a < b }
+
+type Item struct{ P int }
+
+type PtrHeap struct {
+	GenericHeap
+	Push   func(*Item)
+	Pop    func() *Item
+	Remove func(int) *Item
+}
+
+func (h *PtrHeap) Less(a, b *Item) bool { return a.P < b.P }
+
+type Tracked struct{ P, Index int }
+
+type TrackedHeap struct {
+	GenericHeap
+	Push   func(*Tracked)
+	Pop    func() *Tracked
+	Remove func(int) *Tracked
+}
+
+func (h *TrackedHeap) Less(a, b *Tracked) bool { return a.P < b.P }
+
+func newTrackedHeap(opts ...Option) *TrackedHeap {
+	h := new(TrackedHeap)
+	Init(h, append(opts, TrackIndex(func(x interface{}, i int) { x.(*Tracked).Index = i }))...)
+	return h
+}
+
+// checkIndices fails unless every element of h records its own index.
+func checkIndices(t *testing.T, h *TrackedHeap) {
+	t.Helper()
+	for i, v := range h.Heap.Data {
+		if j := v.Interface().(*Tracked).Index; j != i {
+			t.Fatalf("element at index %v records index %v", i, j)
+		}
+	}
+}
+
+type Job struct {
+	Name string
+	Pri  float64
+}
+
+type JobHeap struct {
+	GenericHeap
+	Push   func(Job)
+	Pop    func() Job
+	Remove func(int) Job
+}
+
+func (h *JobHeap) Less(a, b Job) bool { return a.Pri < b.Pri }
+
+// newIntHeap returns an initialized IntHeap holding xs.
+func newIntHeap(xs []int, opts ...Option) *IntHeap {
+	h := new(IntHeap)
+	Init(h, opts...)
+	for _, x := range xs {
+		h.Push(x)
+	}
+	return h
+}
+
+// drain pops every element of h in heap order.
+func drain(h *IntHeap) []int {
+	var out []int
+	for h.Len() > 0 {
+		out = append(out, h.Pop())
+	}
+	return out
+}
+
+// checkOrder fails unless got holds want in the same order.
+func checkOrder(t *testing.T, got, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+// mustPanic fails unless f panics, and returns the recovered value.
+func mustPanic(t *testing.T, name string, f func()) (r interface{}) {
+	t.Helper()
+	defer func() {
+		r = recover()
+		if r == nil {
+			t.Fatalf("%v did not panic", name)
+		}
+	}()
+	f()
+	return nil
+}
+
+func TestPeek(t *testing.T) {
+	h := newIntHeap(nil)
+	if x, ok := h.Peek(); ok {
+		t.Fatalf("Peek on an empty heap = %v, true", x)
+	}
+	for _, x := range []int{5, 3, 8, 1} {
+		h.Push(x)
+	}
+	if x, ok := h.Peek(); !ok || x != 1 {
+		t.Fatalf("Peek() = %v, %v, want 1, true", x, ok)
+	}
+	if h.Len() != 4 {
+		t.Fatalf("Len() after Peek = %v, want 4", h.Len())
+	}
+	checkOrder(t, drain(h), []int{1, 3, 5, 8})
+}
+
+func TestMaxHeap(t *testing.T) {
+	xs := []int{5, 3, 8, 1, 9, 2}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		root int
+		rest []int
+	}{
+		{"min", nil, 1, []int{2, 3, 5, 8, 9}},
+		{"max", []Option{MaxHeap()}, 9, []int{8, 5, 3, 2, 1}},
+	} {
+		h := newIntHeap(xs, tc.opts...)
+		if x, _ := h.Peek(); x != tc.root {
+			t.Fatalf("%v: Peek() = %v, want %v", tc.name, x, tc.root)
+		}
+		if x := h.Remove(0); x != tc.root {
+			t.Fatalf("%v: Remove(0) = %v, want %v", tc.name, x, tc.root)
+		}
+		checkOrder(t, drain(h), tc.rest)
+	}
+
+	h := new(IntHeap)
+	InitMax(h)
+	h.Push(1)
+	h.Push(7)
+	if x := h.Pop(); x != 7 {
+		t.Fatalf("InitMax: Pop() = %v, want 7", x)
+	}
+}
+
+func TestFix(t *testing.T) {
+	h := new(PtrHeap)
+	Init(h)
+	for _, p := range []int{5, 3, 8, 1, 9, 2} {
+		h.Push(&Item{p})
+	}
+	h.Heap.Data[0].Interface().(*Item).P = 100
+	h.Fix(0)
+	h.Heap.Data[4].Interface().(*Item).P = -1
+	h.Fix(4)
+	var got []int
+	for h.Len() > 0 {
+		got = append(got, h.Pop().P)
+	}
+	checkOrder(t, got, []int{-1, 2, 3, 5, 8, 100})
+
+	mustPanic(t, "Fix(0) on an empty heap", func() { h.Fix(0) })
+}
+
+func TestHeapify(t *testing.T) {
+	src := []int{5, 3, 8, 1, 9, 2, 7}
+	h := new(IntHeap)
+	Heapify(h, src)
+	src[0] = -5 // the heap holds its own copy
+	checkOrder(t, drain(h), []int{1, 2, 3, 5, 7, 8, 9})
+
+	e := new(IntHeap)
+	Heapify(e, nil)
+	if e.Len() != 0 {
+		t.Fatalf("Heapify(nil): Len() = %v, want 0", e.Len())
+	}
+
+	r := mustPanic(t, "Heapify with a []string", func() { Heapify(new(IntHeap), []string{"a"}) })
+	if msg, _ := r.(string); !strings.Contains(msg, "expected a slice of int") {
+		t.Fatalf("Heapify with a []string panicked with %q", r)
+	}
+}
+
+func BenchmarkHeapify(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		xs := rand.New(rand.NewSource(1)).Perm(n)
+		b.Run(fmt.Sprintf("Heapify/%v", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Heapify(new(IntHeap), xs)
+			}
+		})
+		b.Run(fmt.Sprintf("Push/%v", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h := new(IntHeap)
+				Init(h)
+				for _, x := range xs {
+					h.Push(x)
+				}
+			}
+		})
+	}
+}
+
+type noLessHeap struct {
+	GenericHeap
+	Push   func(int)
+	Pop    func() int
+	Remove func(int) int
+}
+
+type noPushHeap struct {
+	GenericHeap
+	Pop    func() int
+	Remove func(int) int
+}
+
+func (h *noPushHeap) Less(a, b int) bool { return a < b }
+
+type noPopHeap struct {
+	GenericHeap
+	Push   func(int)
+	Remove func(int) int
+}
+
+func (h *noPopHeap) Less(a, b int) bool { return a < b }
+
+type noRemoveHeap struct {
+	GenericHeap
+	Push func(int)
+	Pop  func() int
+}
+
+func (h *noRemoveHeap) Less(a, b int) bool { return a < b }
+
+func TestInitE(t *testing.T) {
+	for _, tc := range []struct {
+		h    interface{}
+		want string
+	}{
+		{new(noLessHeap), "missing method Less"},
+		{new(noPushHeap), "missing method Push"},
+		{new(noPopHeap), "missing method Pop"},
+		{new(noRemoveHeap), "missing method Remove"},
+		{3, "expected a pointer to a struct"},
+	} {
+		err := InitE(tc.h)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("InitE(%T) = %v, want an error containing %q", tc.h, err, tc.want)
+		}
+	}
+	if err := InitE(new(IntHeap)); err != nil {
+		t.Fatalf("InitE(*IntHeap) = %v", err)
+	}
+
+	r := mustPanic(t, "Init(*noLessHeap)", func() { Init(new(noLessHeap)) })
+	if msg, _ := r.(string); !strings.Contains(msg, "missing method Less") {
+		t.Fatalf("Init(*noLessHeap) panicked with %q", r)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	h := newIntHeap(nil)
+	if !h.IsEmpty() {
+		t.Fatal("IsEmpty() = false for a new heap")
+	}
+	h.Push(1)
+	if h.IsEmpty() {
+		t.Fatal("IsEmpty() = true after Push")
+	}
+	h.Pop()
+	if !h.IsEmpty() {
+		t.Fatal("IsEmpty() = false after popping the last element")
+	}
+}
+
+func TestClear(t *testing.T) {
+	h := newIntHeap([]int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0})
+	c := cap(h.Heap.Data)
+	h.Clear()
+	if h.Len() != 0 {
+		t.Fatalf("Len() after Clear = %v, want 0", h.Len())
+	}
+	if cap(h.Heap.Data) != c {
+		t.Fatalf("cap after Clear = %v, want %v", cap(h.Heap.Data), c)
+	}
+	h.Push(3)
+	if x := h.Pop(); x != 3 {
+		t.Fatalf("Pop() after Clear and Push(3) = %v", x)
+	}
+}
+
+func TestArity(t *testing.T) {
+	for d := 2; d <= 5; d++ {
+		h := new(IntHeap)
+		InitD(h, d)
+		for i := 0; i < 200; i++ {
+			h.Push(i * 7919 % 101)
+		}
+		h.Remove(17)
+		h.Remove(50)
+		got := drain(h)
+		if len(got) != 198 || !sort.IntsAreSorted(got) {
+			t.Fatalf("d=%v: popped %v elements out of order: %v", d, len(got), got)
+		}
+	}
+	if err := InitE(new(IntHeap), Arity(1)); err == nil {
+		t.Fatal("InitE with Arity(1) succeeded")
+	}
+}
+
+// BenchmarkArity pushes ten elements per pop, where a wider heap has
+// shallower sift-ups.
+func BenchmarkArity(b *testing.B) {
+	xs := rand.New(rand.NewSource(1)).Perm(10000)
+	for _, d := range []int{2, 4, 8} {
+		b.Run(fmt.Sprintf("d=%v", d), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h := new(IntHeap)
+				InitD(h, d)
+				for j, x := range xs {
+					h.Push(x)
+					if j%10 == 9 {
+						h.Pop()
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPopAll(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 8, 1, 9, 2})
+	checkOrder(t, h.PopAll(), []int{1, 2, 2, 5, 8, 9})
+	if h.Len() != 0 {
+		t.Fatalf("Len() after PopAll = %v, want 0", h.Len())
+	}
+	if out := h.PopAll(); len(out) != 0 {
+		t.Fatalf("PopAll on an empty heap = %v", out)
+	}
+
+	// Heaps without the field have the method.
+	g := NewIntHeap(LazyDelete())
+	g.PushBatch(3, 1, 2)
+	g.RemoveValue(2)
+	checkOrder(t, g.PopAll().([]int), []int{1, 3})
+	if g.Len() != 0 {
+		t.Fatalf("Len() after GenericHeap.PopAll = %v, want 0", g.Len())
+	}
+}
+
+func TestClone(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 8, 1, 9})
+	c := h.Clone()
+	c.Push(0)
+	var got []int
+	for c.Len() > 0 {
+		got = append(got, c.Pop().(int))
+	}
+	checkOrder(t, got, []int{0, 1, 2, 5, 8, 9})
+	checkOrder(t, drain(h), []int{1, 2, 5, 8, 9})
+
+	m := newIntHeap([]int{5, 2, 8}, MaxHeap())
+	if x := m.Clone().Pop(); x != 8 {
+		t.Fatalf("Pop() on a clone of a max-heap = %v, want 8", x)
+	}
+}
+
+func TestBounded(t *testing.T) {
+	h := new(IntHeap)
+	InitBounded(h, 10)
+	r := rand.New(rand.NewSource(1))
+	xs := make([]int, 10000)
+	for i := range xs {
+		xs[i] = r.Intn(1 << 20)
+		h.Push(xs[i])
+	}
+	if h.Len() != 10 || h.Bound() != 10 {
+		t.Fatalf("Len() = %v, Bound() = %v, want 10, 10", h.Len(), h.Bound())
+	}
+	sort.Ints(xs)
+	checkOrder(t, h.PopAll(), xs[len(xs)-10:])
+}
+
+func TestContains(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 8, 2, 9})
+	for _, tc := range []struct {
+		x    int
+		want bool
+	}{
+		{2, true}, // a duplicate
+		{9, true},
+		{5, true},
+		{3, false},
+		{10, false},
+	} {
+		if got := h.Contains(tc.x); got != tc.want {
+			t.Fatalf("Contains(%v) = %v, want %v", tc.x, got, tc.want)
+		}
+	}
+
+	// Contains compares by Less, so a Job with the same priority matches.
+	j := new(JobHeap)
+	Init(j)
+	j.Push(Job{"a", 1})
+	if !j.Contains(Job{"b", 1}) || j.Contains(Job{"a", 2}) {
+		t.Fatal("Contains did not compare Jobs by priority")
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{1, 5, 3, 8, 6})
+	for _, tc := range []struct {
+		x, want int
+	}{
+		{1, 0}, // the root
+		{8, 3},
+		{3, 2},
+		{4, -1},
+	} {
+		if got := h.IndexOf(tc.x); got != tc.want {
+			t.Fatalf("IndexOf(%v) = %v, want %v", tc.x, got, tc.want)
+		}
+	}
+	h.Remove(h.IndexOf(8))
+	if h.Contains(8) {
+		t.Fatal("Contains(8) after Remove(IndexOf(8))")
+	}
+}
+
+func TestValues(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{2, 5, 8})
+	v := h.Values()
+	if len(v) != 3 || v[0] != 2 || v[1] != 5 || v[2] != 8 {
+		t.Fatalf("Values() = %v, want [2 5 8]", v)
+	}
+	v[0] = 100
+	if x := h.Pop(); x != 2 {
+		t.Fatalf("Pop() after changing the Values copy = %v, want 2", x)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 8, 1})
+	g := newIntHeap([]int{9, 0, 3, 7, 4, 6})
+	h.Merge(&g.GenericHeap)
+	if g.Len() != 6 {
+		t.Fatalf("Merge changed the other heap: Len() = %v, want 6", g.Len())
+	}
+	checkOrder(t, h.PopAll(), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	p := new(PtrHeap)
+	Init(p)
+	mustPanic(t, "Merge of a *Item heap into an int heap", func() { h.Merge(&p.GenericHeap) })
+	mustPanic(t, "Merge of a max-heap into a min-heap", func() {
+		h.Merge(&newIntHeap(nil, MaxHeap()).GenericHeap)
+	})
+}
+
+func TestPushBatch(t *testing.T) {
+	xs := []interface{}{9, 0, 3, 7, 4, 6}
+	h := newIntHeap([]int{5, 2})
+	h.PushBatch(xs...)
+	g := newIntHeap([]int{5, 2})
+	for _, x := range xs {
+		g.Push(x.(int))
+	}
+	checkOrder(t, h.PopAll(), g.PopAll())
+
+	h.PushBatch()
+	if h.Len() != 0 {
+		t.Fatalf("Len() after an empty PushBatch = %v, want 0", h.Len())
+	}
+}
+
+func BenchmarkPushBatch(b *testing.B) {
+	perm := rand.New(rand.NewSource(1)).Perm(10000)
+	xs := make([]interface{}, len(perm))
+	for i, x := range perm {
+		xs[i] = x
+	}
+	b.Run("PushBatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h := newIntHeap(perm[:100])
+			h.PushBatch(xs...)
+		}
+	})
+	b.Run("Push", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h := newIntHeap(perm[:100])
+			for _, x := range perm {
+				h.Push(x)
+			}
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{5, 2, 9, 0, 3, 7})
+	if err := h.Validate(); err != nil {
+		t.Fatalf("Validate() = %v for a valid heap", err)
+	}
+	h.Heap.Data[0] = reflect.ValueOf(100)
+	if err := h.Validate(); err == nil || !strings.Contains(err.Error(), "index 0") {
+		t.Fatalf("Validate() = %v after corrupting the root", err)
+	}
+}
+
+func TestNewFunc(t *testing.T) {
+	h := NewFunc(reflect.TypeOf(""), func(a, b interface{}) bool { return a.(string) < b.(string) })
+	for _, s := range []string{"q", "b", "z", "a"} {
+		h.Push(s)
+	}
+	var got []string
+	for h.Len() > 0 {
+		got = append(got, h.Pop().(string))
+	}
+	if strings.Join(got, " ") != "a b q z" {
+		t.Fatalf("popped %v, want [a b q z]", got)
+	}
+	mustPanic(t, "Push(1) on a string heap", func() { h.Push(1) })
+}
+
+func TestString(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{5, 8, 7})
+	if s := fmt.Sprint(h); s != "Heap[len=3, top=5, data=[5 8 7]]" {
+		t.Fatalf("String() = %q", s)
+	}
+	if s := fmt.Sprint(newIntHeap(nil)); s != "Heap[len=0]" {
+		t.Fatalf("String() of an empty heap = %q", s)
+	}
+
+	g := new(IntHeap)
+	xs := make([]int, 25)
+	for i := range xs {
+		xs[i] = i
+	}
+	Heapify(g, xs)
+	want := "Heap[len=25, top=0, data=[0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 ...]]"
+	if s := g.String(); s != want {
+		t.Fatalf("String() = %q, want %q", s, want)
+	}
+}
+
+func TestRemoveValue(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		x    int
+	}{
+		{"root", 0},
+		{"interior", 2},
+		{"leaf", 6},
+	} {
+		h := new(IntHeap)
+		Heapify(h, []int{0, 2, 1, 4, 3, 5, 6})
+		if !h.RemoveValue(tc.x) {
+			t.Fatalf("%v: RemoveValue(%v) = false", tc.name, tc.x)
+		}
+		if err := h.Validate(); err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		if h.Len() != 6 || h.Contains(tc.x) {
+			t.Fatalf("%v: heap still holds %v after RemoveValue: %v", tc.name, tc.x, h)
+		}
+		if h.RemoveValue(100) {
+			t.Fatalf("%v: RemoveValue(100) = true", tc.name)
+		}
+	}
+}
+
+func TestChangeKey(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{0, 2, 1, 4, 3, 5, 7})
+	if err := h.DecreaseKey(h.IndexOf(7), -1); err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := h.Peek(); x != -1 {
+		t.Fatalf("root after DecreaseKey to -1 = %v", x)
+	}
+	if err := h.IncreaseKey(0, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, h.PopAll(), []int{0, 1, 2, 3, 4, 5, 100})
+
+	h.PushBatch(1, 2, 3)
+	for _, err := range []error{
+		h.IncreaseKey(1, 0),
+		h.DecreaseKey(1, 10),
+		h.DecreaseKey(99, 0),
+	} {
+		if err == nil {
+			t.Fatal("misuse of DecreaseKey or IncreaseKey returned no error")
+		}
+	}
+
+	m := new(IntHeap)
+	Heapify(m, []int{5, 2, 9, 0, 3}, MaxHeap())
+	if err := m.IncreaseKey(m.IndexOf(0), 50); err != nil {
+		t.Fatal(err)
+	}
+	if x := m.Pop(); x != 50 {
+		t.Fatalf("Pop() from a max-heap after IncreaseKey to 50 = %v", x)
+	}
+}
+
+func TestTrackIndex(t *testing.T) {
+	h := newTrackedHeap()
+	var all []*Tracked
+	for i := 0; i < 50; i++ {
+		e := &Tracked{P: i * 37 % 23}
+		all = append(all, e)
+		h.Push(e)
+	}
+	h.Remove(10)
+	h.PushBatch(&Tracked{P: 3}, &Tracked{P: 99})
+	if e := all[20]; e.Index >= 0 {
+		e.P = -5
+		h.Fix(e.Index)
+	}
+	checkIndices(t, h)
+
+	if x := h.Pop(); x.Index != -1 {
+		t.Fatalf("popped element records index %v, want -1", x.Index)
+	}
+	checkIndices(t, h)
+}
+
+func TestTopK(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 9, 0, 3})
+	checkOrder(t, h.TopK(3), []int{0, 2, 3})
+	if h.Len() != 5 {
+		t.Fatalf("Len() after TopK = %v, want 5", h.Len())
+	}
+	checkOrder(t, h.TopK(30), []int{0, 2, 3, 5, 9})
+	checkOrder(t, h.PopAll(), []int{0, 2, 3, 5, 9})
+
+	// Heaps without the field have the method.
+	g := NewIntHeap()
+	g.PushBatch(5, 2, 9, 0, 3)
+	checkOrder(t, g.TopK(2).([]int), []int{0, 2})
+	checkOrder(t, g.Clone().TopK(-1).([]int), nil)
+	checkOrder(t, g.PopAll().([]int), []int{0, 2, 3, 5, 9})
+}
+
+func TestIterate(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 9, 0, 3})
+	var got []int
+	h.Iterate(func(x interface{}) bool {
+		got = append(got, x.(int))
+		return true
+	})
+	checkOrder(t, got, []int{0, 2, 3, 5, 9})
+
+	got = nil
+	h.Iterate(func(x interface{}) bool {
+		got = append(got, x.(int))
+		return len(got) < 3
+	})
+	checkOrder(t, got, []int{0, 2, 3})
+	if h.Len() != 5 {
+		t.Fatalf("Len() after Iterate = %v, want 5", h.Len())
+	}
+}
+
+// BenchmarkLess measures Push and Pop through the direct call path taken
+// for int comparators and through reflect.Call for struct elements.
+func BenchmarkLess(b *testing.B) {
+	xs := rand.New(rand.NewSource(1)).Perm(1000)
+	b.Run("int", func(b *testing.B) {
+		h := newIntHeap(xs)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Push(h.Pop())
+		}
+	})
+	b.Run("struct", func(b *testing.B) {
+		h := new(JobHeap)
+		Init(h)
+		for _, x := range xs {
+			h.Push(Job{Pri: float64(x)})
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Push(h.Pop())
+		}
+	})
+}
+
+func TestPopEmpty(t *testing.T) {
+	h := newIntHeap(nil)
+	r := mustPanic(t, "Pop on an empty heap", func() { h.Pop() })
+	if r != "Pop called on an empty heap" {
+		t.Fatalf("Pop on an empty heap panicked with %v", r)
+	}
+	if x, ok := h.TryPop(); ok {
+		t.Fatalf("TryPop() on an empty heap = %v, true", x)
+	}
+
+	o := new(OkHeap)
+	Init(o)
+	if x, ok := o.Pop(); ok || x != 0 {
+		t.Fatalf("Pop() on an empty OkHeap = %v, %v, want 0, false", x, ok)
+	}
+	o.Push(4)
+	if x, ok := o.Pop(); !ok || x != 4 {
+		t.Fatalf("Pop() = %v, %v, want 4, true", x, ok)
+	}
+}
+
+func TestRemoveIndexRange(t *testing.T) {
+	h := newIntHeap([]int{4, 1, 3, 2})
+	for _, i := range []int{-1, 4} {
+		r := mustPanic(t, fmt.Sprintf("Remove(%v)", i), func() { h.Remove(i) })
+		want := fmt.Sprintf("Remove index %v out of range for heap of length 4", i)
+		if r != want {
+			t.Fatalf("Remove(%v) panicked with %q, want %q", i, r, want)
+		}
+	}
+	x := h.Heap.Data[1].Interface()
+	if got := h.Remove(1); got != x {
+		t.Fatalf("Remove(1) = %v, want %v", got, x)
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThreeWayLess(t *testing.T) {
+	h := new(CmpHeap)
+	Heapify(h, []string{"d", "a", "c", "b"})
+	var got []string
+	for h.Len() > 0 {
+		got = append(got, h.Pop())
+	}
+	if strings.Join(got, "") != "abcd" {
+		t.Fatalf("popped %v, want [a b c d]", got)
+	}
+
+	err := InitE(new(badLessHeap))
+	if err == nil || !strings.Contains(err.Error(), "bool or int") {
+		t.Fatalf("InitE with a string-returning Less = %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	xs := []int{5, 2, 9, 0, 3}
+	h := newIntHeap(xs)
+	g := newIntHeap(xs)
+	for _, x := range []int{7, -1, 4, 10} {
+		got := h.Replace(x)
+		want := g.Pop()
+		g.Push(x)
+		if got != want {
+			t.Fatalf("Replace(%v) = %v, want %v as from Pop", x, got, want)
+		}
+	}
+	checkOrder(t, h.PopAll(), g.PopAll())
+	mustPanic(t, "Replace on an empty heap", func() { h.Replace(1) })
+}
+
+func BenchmarkReplace(b *testing.B) {
+	xs := rand.New(rand.NewSource(1)).Perm(1000)
+	b.Run("Replace", func(b *testing.B) {
+		h := newIntHeap(xs)
+		for i := 0; i < b.N; i++ {
+			h.Replace(xs[i%len(xs)])
+		}
+	})
+	b.Run("PopPush", func(b *testing.B) {
+		h := newIntHeap(xs)
+		for i := 0; i < b.N; i++ {
+			h.Pop()
+			h.Push(xs[i%len(xs)])
+		}
+	})
+}
+
+func TestPushPop(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 9})
+	if x := h.PushPop(1); x != 1 || h.Len() != 3 {
+		t.Fatalf("PushPop(1) = %v with Len() %v, want 1 with the heap unchanged", x, h.Len())
+	}
+	if x := h.PushPop(6); x != 2 {
+		t.Fatalf("PushPop(6) = %v, want the old root 2", x)
+	}
+	checkOrder(t, h.PopAll(), []int{5, 6, 9})
+	if x := h.PushPop(3); x != 3 || h.Len() != 0 {
+		t.Fatalf("PushPop(3) on an empty heap = %v with Len() %v", x, h.Len())
+	}
+}
+
+func TestReserve(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 9})
+	h.Reserve(100)
+	if c := cap(h.Heap.Data); c < 100 || h.Len() != 3 {
+		t.Fatalf("after Reserve(100): cap %v, Len() %v, want at least 100 and 3", c, h.Len())
+	}
+	first := &h.Heap.Data[0]
+	for i := 0; i < 97; i++ {
+		h.Push(i)
+	}
+	if first != &h.Heap.Data[0] {
+		t.Fatal("pushing up to the reserved capacity reallocated Data")
+	}
+}
+
+func TestStable(t *testing.T) {
+	h := new(JobHeap)
+	InitStable(h)
+	var want []string
+	for i := 0; i < 30; i++ {
+		name := fmt.Sprint(i)
+		pri := 1.0
+		if i%3 == 0 {
+			pri = float64(i % 7)
+		}
+		if pri == 1 {
+			want = append(want, name)
+		}
+		h.Push(Job{name, pri})
+	}
+	var got []string
+	for h.Len() > 0 {
+		if j := h.Pop(); j.Pri == 1 {
+			got = append(got, j.Name)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("equal-priority jobs popped as %v, want insertion order %v", got, want)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	h := newIntHeap([]int{5, 2, 9, 0, 3})
+	var got []int
+	h.Drain(func(x interface{}) { got = append(got, x.(int)) })
+	checkOrder(t, got, []int{0, 2, 3, 5, 9})
+	if h.Len() != 0 {
+		t.Fatalf("Len() after Drain = %v, want 0", h.Len())
+	}
+}
+
+func TestStats(t *testing.T) {
+	h := newIntHeap([]int{3, 1, 2}, CollectStats())
+	h.Pop()
+	want := Stats{Pushes: 3, Pops: 1, Swaps: 2, Len: 2, PeakLen: 3}
+	if s := h.Stats(); s != want {
+		t.Fatalf("Stats() = %+v, want %+v", s, want)
+	}
+	if s := newIntHeap([]int{1}).Stats(); s != (Stats{Len: 1}) {
+		t.Fatalf("Stats() without CollectStats = %+v", s)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	h := newIntHeap(nil)
+	for i := 0; i < 1000; i++ {
+		h.Push(i)
+	}
+	for i := 0; i < 990; i++ {
+		h.Pop()
+	}
+	h.Compact()
+	if c := cap(h.Heap.Data); c != h.Len() {
+		t.Fatalf("cap after Compact = %v, want Len() %v", c, h.Len())
+	}
+	if x := h.Pop(); x != 990 {
+		t.Fatalf("Pop() after Compact = %v, want 990", x)
+	}
+}
+
+func TestRemoveFunc(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{5, 2, 9, 0, 3, 8, 6, 1})
+	even := func(x interface{}) bool { return x.(int)%2 == 0 }
+	if n := h.RemoveFunc(even); n != 4 {
+		t.Fatalf("RemoveFunc(even) = %v, want 4", n)
+	}
+	if n := h.RemoveFunc(even); n != 0 {
+		t.Fatalf("RemoveFunc(even) with no even elements = %v, want 0", n)
+	}
+	checkOrder(t, h.PopAll(), []int{1, 3, 5, 9})
+
+	// The single rebuild keeps tracked indices up to date.
+	g := newTrackedHeap()
+	for i := 0; i < 50; i++ {
+		g.Push(&Tracked{P: i * 37 % 23})
+	}
+	g.RemoveFunc(func(x interface{}) bool { return x.(*Tracked).P%3 == 0 })
+	checkIndices(t, g)
+}
+
+func TestPushTypeMismatch(t *testing.T) {
+	h := newIntHeap([]int{3})
+	for _, tc := range []struct {
+		x    interface{}
+		want string
+	}{
+		{"x", "type mismatch: expected int, got string"},
+		{nil, "type mismatch: expected int, got nil"},
+		{int64(1), "type mismatch: expected int, got int64"},
+	} {
+		r := mustPanic(t, fmt.Sprintf("Push(%#v)", tc.x), func() { h.GenericHeap.Push(tc.x) })
+		if r != tc.want {
+			t.Fatalf("Push(%#v) panicked with %q, want %q", tc.x, r, tc.want)
+		}
+	}
+	h.GenericHeap.Push(1)
+	checkOrder(t, h.PopAll(), []int{1, 3})
+
+	// nil is a valid element of a nilable element type.
+	p := new(PtrHeap)
+	Init(p)
+	p.GenericHeap.Push(nil)
+	if x := p.Pop(); x != nil {
+		t.Fatalf("Pop() = %v, want the nil pushed", x)
+	}
+}
+
+func TestOnSwap(t *testing.T) {
+	var swaps [][2]int
+	h := new(IntHeap)
+	Init(h, OnSwap(func(i, j int) { swaps = append(swaps, [2]int{i, j}) }))
+	for _, x := range []int{3, 2, 1} {
+		h.Push(x)
+	}
+	// 2 swaps with its parent 3, then 1 swaps with its parent 2.
+	if fmt.Sprint(swaps) != "[[0 1] [0 2]]" {
+		t.Fatalf("OnSwap saw %v, want [[0 1] [0 2]]", swaps)
+	}
+	swaps = nil
+	h.Pop()
+	if fmt.Sprint(swaps) != "[[0 2]]" {
+		t.Fatalf("OnSwap saw %v during Pop, want [[0 2]]", swaps)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	h := Define(reflect.TypeOf(0), func(a, b int) bool { return a > b })
+	h.PushBatch(3, 9, 1)
+	if x := h.Pop(); x != 9 {
+		t.Fatalf("Pop() = %v, want 9", x)
+	}
+
+	j := Define(reflect.TypeOf(Job{}), func(a, b Job) int { return int(a.Pri - b.Pri) })
+	j.Push(Job{"x", 5})
+	j.Push(Job{"y", 2})
+	if x := j.Pop().(Job); x.Name != "y" {
+		t.Fatalf("Pop() = %v, want job y", x)
+	}
+
+	mustPanic(t, "Define with a comparator of another type", func() {
+		Define(reflect.TypeOf(0), func(a, b string) bool { return a < b })
+	})
+	mustPanic(t, "Define with a non-func comparator", func() { Define(reflect.TypeOf(0), 3) })
+}
+
+func TestMinMax(t *testing.T) {
+	if x := newIntHeap(nil).Max(); x != nil {
+		t.Fatalf("Max() of an empty heap = %v, want nil", x)
+	}
+	for n := 1; n < 40; n++ {
+		for _, opts := range [][]Option{nil, {Arity(3)}, {MaxHeap()}} {
+			h := newIntHeap(nil, opts...)
+			lo, hi := 1000, -1
+			for i := 0; i < n; i++ {
+				x := i * 7919 % 101
+				h.Push(x)
+				if x < lo {
+					lo = x
+				}
+				if x > hi {
+					hi = x
+				}
+			}
+			if x := h.Min(); x != lo {
+				t.Fatalf("n=%v: Min() = %v, want %v", n, x, lo)
+			}
+			if x := h.Max(); x != hi {
+				t.Fatalf("n=%v: Max() = %v, want %v", n, x, hi)
+			}
+		}
+	}
+}
+
+func TestPushSlice(t *testing.T) {
+	h := newIntHeap([]int{2})
+	src := []int{4, 1, 3}
+	h.PushSlice(src)
+	src[1] = 100
+	checkOrder(t, h.PopAll(), []int{1, 2, 3, 4})
+
+	j := new(JobHeap)
+	Init(j)
+	j.PushSlice([]Job{{"a", 2}, {"b", 1}})
+	if x := j.Pop(); x.Name != "b" {
+		t.Fatalf("Pop() = %v, want job b", x)
+	}
+	r := mustPanic(t, "PushSlice of a []int into a Job heap", func() { j.PushSlice([]int{1}) })
+	if msg, _ := r.(string); !strings.Contains(msg, "expected a slice of heap.Job") {
+		t.Fatalf("PushSlice of a []int panicked with %q", r)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := new(IntHeap)
+	Heapify(a, []int{1, 2, 3, 4, 5})
+	b := newIntHeap([]int{5, 4, 3, 2, 1})
+	if fmt.Sprint(a.Values()) == fmt.Sprint(b.Values()) {
+		t.Fatal("test heaps should differ in array order")
+	}
+	for _, tc := range []struct {
+		other *IntHeap
+		want  bool
+	}{
+		{b, true},
+		{newIntHeap([]int{1, 2, 3, 4, 6}), false},
+		{newIntHeap([]int{1, 2, 3, 4}), false},
+		{newIntHeap([]int{1, 1, 2, 3, 4}), false},
+	} {
+		if got := a.Equal(&tc.other.GenericHeap); got != tc.want {
+			t.Fatalf("%v.Equal(%v) = %v, want %v", a, tc.other, got, tc.want)
+		}
+	}
+}
+
+func TestLessPanic(t *testing.T) {
+	h := NewFunc(reflect.TypeOf(0), func(a, b interface{}) bool {
+		if a.(int) == 13 || b.(int) == 13 {
+			panic("unlucky")
+		}
+		return a.(int) < b.(int)
+	})
+	h.Push(1)
+	r := mustPanic(t, "Push(13) with a panicking Less", func() { h.Push(13) })
+	msg, _ := r.(string)
+	if !strings.Contains(msg, "Less panicked comparing") || !strings.Contains(msg, "13 at index 1") || !strings.Contains(msg, "unlucky") {
+		t.Fatalf("Push(13) panicked with %q", r)
+	}
+}
+
+func TestLazyDelete(t *testing.T) {
+	var seqs [2][]int
+	for k, opts := range [][]Option{nil, {LazyDelete()}} {
+		h := newIntHeap(nil, opts...)
+		for i := 0; i < 200; i++ {
+			h.Push(i * 7919 % 211)
+		}
+		for i := 0; i < 150; i++ {
+			if !h.RemoveValue(i * 31 % 211) {
+				continue
+			}
+			if i%7 == 0 {
+				seqs[k] = append(seqs[k], h.Pop())
+			}
+			if i%11 == 0 {
+				h.Push(i)
+			}
+		}
+		if d := h.Heap.NumDead; d*2 > len(h.Heap.Data) {
+			t.Fatalf("%v deleted elements left among %v", d, len(h.Heap.Data))
+		}
+		if n := h.Len(); n != len(h.Heap.Data)-h.Heap.NumDead {
+			t.Fatalf("Len() = %v for %v elements with %v deleted", n, len(h.Heap.Data), h.Heap.NumDead)
+		}
+		seqs[k] = append(seqs[k], drain(h)...)
+	}
+	checkOrder(t, seqs[1], seqs[0])
+}
+
+func TestLazyDeleteDeadIndex(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{1, 2, 3, 4, 5, 6}, LazyDelete())
+	i := h.IndexOf(5)
+	h.RemoveValue(5)
+	if h.Len() != 5 || h.Heap.NumDead != 1 {
+		t.Fatalf("Len() = %v with %v deleted, want 5 with 1 deleted", h.Len(), h.Heap.NumDead)
+	}
+
+	for name, f := range map[string]func(){
+		"Remove":       func() { h.Remove(i) },
+		"At":           func() { h.At(i) },
+		"Set":          func() { h.Set(i, 7) },
+		"Reprioritize": func() { h.Reprioritize(i, 7) },
+		"Fix":          func() { h.Fix(i) },
+	} {
+		r := mustPanic(t, name+" of a deleted index", f)
+		if msg, _ := r.(string); !strings.Contains(msg, "deleted element") {
+			t.Fatalf("%v of a deleted index panicked with %q", name, r)
+		}
+	}
+	if err := h.DecreaseKey(i, 0); err == nil {
+		t.Fatal("DecreaseKey of a deleted index succeeded")
+	}
+
+	h.MapInPlace(func(x interface{}) interface{} { return x.(int) * 10 })
+	if h.Len() != 5 || h.Heap.NumDead != 1 {
+		t.Fatalf("after MapInPlace: Len() = %v with %v deleted", h.Len(), h.Heap.NumDead)
+	}
+	checkOrder(t, drain(h), []int{10, 20, 30, 40, 60})
+	if len(h.Heap.Data) != 0 || h.Heap.NumDead != 0 {
+		t.Fatalf("drained heap holds %v elements with %v deleted", len(h.Heap.Data), h.Heap.NumDead)
+	}
+}
+
+func TestPopN(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{5, 2, 9, 0, 3, 8})
+	checkOrder(t, h.PopN(3).([]int), []int{0, 2, 3})
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 3 {
+		t.Fatalf("Len() after PopN(3) = %v, want 3", h.Len())
+	}
+	checkOrder(t, h.PopN(30).([]int), []int{5, 8, 9})
+	if out := h.PopN(1).([]int); len(out) != 0 {
+		t.Fatalf("PopN(1) on an empty heap = %v", out)
+	}
+}
+
+func TestHeapifyMethod(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{5, 2, 9, 0, 3, 8})
+	for i := range h.Heap.Data {
+		h.Heap.Data[i] = reflect.ValueOf(10 - i)
+	}
+	if h.Validate() == nil {
+		t.Fatal("scrambled heap still validates")
+	}
+	h.Heapify()
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, h.PopAll(), []int{5, 6, 7, 8, 9, 10})
+}
+
+func TestElemType(t *testing.T) {
+	j := new(JobHeap)
+	Init(j)
+	for _, tc := range []struct {
+		h    *GenericHeap
+		want reflect.Type
+	}{
+		{&j.GenericHeap, reflect.TypeOf(Job{})},
+		{&newIntHeap(nil).GenericHeap, reflect.TypeOf(0)},
+		{NewStringHeap(), reflect.TypeOf("")},
+	} {
+		if got := tc.h.ElemType(); got != tc.want {
+			t.Fatalf("ElemType() = %v, want %v", got, tc.want)
+		}
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	h := new(ErrHeap)
+	Init(h, MaxSize(2))
+	for _, x := range []int{1, 2} {
+		if err := h.Push(x); err != nil {
+			t.Fatalf("Push(%v) = %v", x, err)
+		}
+	}
+	if err := h.Push(3); err == nil || !strings.Contains(err.Error(), "heap full") {
+		t.Fatalf("Push(3) beyond MaxSize(2) = %v", err)
+	}
+	if h.Len() != 2 {
+		t.Fatalf("Len() = %v, want 2", h.Len())
+	}
+
+	g := NewIntHeap(MaxSize(1))
+	if err := g.PushE(1); err != nil {
+		t.Fatalf("PushE(1) = %v", err)
+	}
+	if err := g.PushE(1); err == nil {
+		t.Fatal("PushE beyond MaxSize(1) succeeded")
+	}
+	r := mustPanic(t, "Push beyond MaxSize(1)", func() { g.Push(4) })
+	if msg, _ := r.(string); !strings.Contains(msg, "heap full") {
+		t.Fatalf("Push beyond MaxSize(1) panicked with %q", r)
+	}
+}
+
+func TestSift(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{1, 2, 3, 4, 5, 6})
+	h.Heap.Data[5] = reflect.ValueOf(0)
+	h.SiftUp(5)
+	if x, _ := h.Peek(); x != 0 {
+		t.Fatalf("root after SiftUp of 0 = %v, want 0", x)
+	}
+	h.Heap.Data[0] = reflect.ValueOf(10)
+	h.SiftDown(0)
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, h.PopAll(), []int{1, 2, 3, 4, 5, 10})
+	mustPanic(t, "SiftUp(0) on an empty heap", func() { h.SiftUp(0) })
+	mustPanic(t, "SiftDown(-1)", func() { h.SiftDown(-1) })
+}
+
+func TestCopyOnPush(t *testing.T) {
+	h := new(PtrHeap)
+	Init(h, CopyOnPush())
+	a := &Item{5}
+	h.Push(a)
+	h.Push(&Item{3})
+	a.P = 1
+	if x := h.Pop(); x.P != 3 || x == a {
+		t.Fatalf("Pop() = %v, want a copy of Item 3", x)
+	}
+	if x := h.Pop(); x.P != 5 || x == a {
+		t.Fatalf("Pop() = %v, want a copy of Item 5 unchanged by its original", x)
+	}
+
+	// Heapify copies the elements of its slice too.
+	b := &Item{2}
+	g := new(PtrHeap)
+	Heapify(g, []*Item{b, {4}}, CopyOnPush())
+	b.P = 9
+	if x := g.Pop(); x.P != 2 || x == b {
+		t.Fatalf("Pop() after Heapify = %v, want a copy of Item 2", x)
+	}
+}
+
+func TestTextbookAliases(t *testing.T) {
+	h := NewIntHeap()
+	if x, ok := h.FindMin(); ok {
+		t.Fatalf("FindMin() on an empty heap = %v, true", x)
+	}
+	mustPanic(t, "ExtractMin on an empty heap", func() { h.ExtractMin() })
+	h.Insert(3)
+	h.Insert(1)
+	if x, ok := h.FindMin(); !ok || x != 1 {
+		t.Fatalf("FindMin() = %v, %v, want 1, true", x, ok)
+	}
+	if x := h.ExtractMin(); x != 1 || h.Len() != 1 {
+		t.Fatalf("ExtractMin() = %v with Len() %v, want 1 with 1 left", x, h.Len())
+	}
+}
+
+func TestNewKeyFunc(t *testing.T) {
+	calls := 0
+	key := func(x interface{}) float64 {
+		calls++
+		return x.(Job).Pri
+	}
+	h := NewKeyFunc(reflect.TypeOf(Job{}), key)
+	for i := 0; i < 100; i++ {
+		h.Push(Job{fmt.Sprint(i), float64(i * 37 % 101)})
+	}
+	if calls != 100 {
+		t.Fatalf("key called %v times for 100 pushes, want once per element", calls)
+	}
+	h.RemoveFunc(func(x interface{}) bool { return x.(Job).Pri > 50 })
+	var got []float64
+	for h.Len() > 0 {
+		got = append(got, h.Pop().(Job).Pri)
+	}
+	if len(got) != 51 || !sort.Float64sAreSorted(got) {
+		t.Fatalf("popped keys %v, want the 51 keys up to 50 in order", got)
+	}
+
+	m := NewKeyFunc(reflect.TypeOf(Job{}), func(x interface{}) float64 { return x.(Job).Pri }, MaxHeap(), Stable())
+	m.PushBatch(Job{"a", 1}, Job{"b", 3}, Job{"c", 3}, Job{"d", 2})
+	var names string
+	for m.Len() > 0 {
+		names += m.Pop().(Job).Name
+	}
+	if names != "bcda" {
+		t.Fatalf("stable max-heap by key popped %q, want %q", names, "bcda")
+	}
+}
+
+func TestReprioritize(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(5, 3, 8, 1, 9, 7)
+	h.Reprioritize(h.IndexOf(9), 0) // moves up
+	if x, _ := h.Peek(); x != 0 {
+		t.Fatalf("root after Reprioritize to 0 = %v", x)
+	}
+	h.Reprioritize(0, 100) // moves down
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, popInts(h), []int{1, 3, 5, 7, 8, 100})
+	mustPanic(t, "Reprioritize(0) on an empty heap", func() { h.Reprioritize(0, 1) })
+}
+
+func TestTieBreak(t *testing.T) {
+	type P struct{ K, ID int }
+	byID := TieBreak(func(a, b interface{}) bool { return a.(P).ID < b.(P).ID })
+	pop := func(h *GenericHeap, order []int) string {
+		for _, id := range order {
+			h.Push(P{id % 3, id})
+		}
+		var out []interface{}
+		h.Drain(func(x interface{}) { out = append(out, x) })
+		return fmt.Sprint(out)
+	}
+	want := "[{0 0} {0 3} {0 6} {1 1} {1 4} {1 7} {2 2} {2 5} {2 8}]"
+	for _, order := range [][]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8},
+		{8, 7, 6, 5, 4, 3, 2, 1, 0},
+		{4, 0, 8, 2, 6, 1, 5, 3, 7},
+	} {
+		h := NewFunc(reflect.TypeOf(P{}), func(a, b interface{}) bool { return a.(P).K < b.(P).K }, byID)
+		if got := pop(h, order); got != want {
+			t.Fatalf("pushing %v popped %v, want %v", order, got, want)
+		}
+		k := NewKeyFunc(reflect.TypeOf(P{}), func(a interface{}) float64 { return float64(a.(P).K) }, byID, Stable())
+		if got := pop(k, order); got != want {
+			t.Fatalf("key heap: pushing %v popped %v, want %v", order, got, want)
+		}
+	}
+}
+
+func TestPopWhile(t *testing.T) {
+	type event struct {
+		At   int
+		Name string
+	}
+	h := NewFunc(reflect.TypeOf(event{}), func(a, b interface{}) bool { return a.(event).At < b.(event).At })
+	h.PushBatch(event{50, "e"}, event{10, "a"}, event{40, "d"}, event{20, "b"}, event{30, "c"}, event{60, "f"})
+	now := 35
+	due := h.PopWhile(func(x interface{}) bool { return x.(event).At <= now })
+	if fmt.Sprint(due) != "[{10 a} {20 b} {30 c}]" || h.Len() != 3 {
+		t.Fatalf("PopWhile(due by %v) = %v leaving %v, want the first three events", now, due, h.Len())
+	}
+	if got := h.PopWhile(func(x interface{}) bool { return false }); got != nil {
+		t.Fatalf("PopWhile(false) = %v, want nil", got)
+	}
+	if got := h.PopWhile(func(x interface{}) bool { return true }); len(got) != 3 || h.Len() != 0 {
+		t.Fatalf("PopWhile(true) = %v, want the remaining 3 events", got)
+	}
+}
+
+func TestCountFunc(t *testing.T) {
+	odd := func(x interface{}) bool { return x.(int)%2 == 1 }
+	h := NewIntHeap(LazyDelete())
+	h.PushBatch(5, 3, 8, 1, 9, 7, 2)
+	before := fmt.Sprint(h.Values())
+	if n := h.CountFunc(odd); n != 5 {
+		t.Fatalf("CountFunc(odd) = %v, want 5", n)
+	}
+	if after := fmt.Sprint(h.Values()); after != before {
+		t.Fatalf("CountFunc changed the heap from %v to %v", before, after)
+	}
+	h.RemoveValue(9)
+	if n := h.CountFunc(odd); n != 4 {
+		t.Fatalf("CountFunc(odd) after deleting 9 = %v, want 4", n)
+	}
+}
+
+func TestAppendUnsorted(t *testing.T) {
+	h := NewIntHeap(Stable())
+	for _, x := range []int{9, 3, 7, 1, 8, 2} {
+		h.AppendUnsorted(x)
+	}
+	h.Heapify()
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, popInts(h), []int{1, 2, 3, 7, 8, 9})
+	mustPanic(t, "AppendUnsorted on a bounded heap", func() { NewIntHeap(Bounded(2)).AppendUnsorted(1) })
+}
+
+func TestSetLess(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(5, 3, 8, 1)
+	h.SetLess(func(a, b int) bool { return a > b })
+	checkOrder(t, h.PopN(2).([]int), []int{8, 5})
+	h.SetLess(func(a, b interface{}) bool { return a.(int) < b.(int) })
+	checkOrder(t, h.PopN(2).([]int), []int{1, 3})
+
+	// A typed comparator replaces the key of a key heap.
+	k := NewKeyFunc(reflect.TypeOf(0), func(x interface{}) float64 { return float64(x.(int)) })
+	k.PushBatch(1, 2, 3)
+	k.SetLess(Reverse(func(a, b int) bool { return a < b }))
+	checkOrder(t, k.PopN(3).([]int), []int{3, 2, 1})
+
+	mustPanic(t, "SetLess with a string comparator", func() { h.SetLess(func(a, b string) bool { return a < b }) })
+	mustPanic(t, "SetLess with a non-func", func() { h.SetLess(1) })
+}
+
+func TestCoalesce(t *testing.T) {
+	type C struct {
+		Key string
+		N   int
+	}
+	h := NewFunc(reflect.TypeOf(C{}), func(a, b interface{}) bool { return a.(C).N < b.(C).N },
+		Coalesce(func(a, b interface{}) bool { return a.(C).Key == b.(C).Key },
+			func(e, in interface{}) interface{} { return C{e.(C).Key, e.(C).N + in.(C).N} }),
+		MaxSize(2))
+	h.Push(C{"a", 1})
+	h.Push(C{"b", 5})
+	h.Push(C{"a", 2})
+	// Coalescing into an existing key is allowed on a full heap.
+	if err := h.PushE(C{"a", 10}); err != nil {
+		t.Fatalf("PushE of an existing key into a full heap = %v", err)
+	}
+	h.PushBatch(C{"b", 1})
+	if err := h.PushE(C{"c", 1}); !errors.Is(err, ErrFull) {
+		t.Fatalf("PushE of a new key into a full heap = %v, want ErrFull", err)
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(h.Pop(), h.Pop()); got != "{b 6} {a 13}" {
+		t.Fatalf("popped %v, want {b 6} {a 13}", got)
+	}
+
+	// The single-sift methods coalesce too.
+	h.PushBatch(C{"a", 1}, C{"b", 5})
+	if x := h.PushPop(C{"b", 1}); x != (C{"a", 1}) {
+		t.Fatalf("PushPop({b 1}) = %v, want {a 1}", x)
+	}
+	h.Push(C{"a", 1})
+	if x := h.Replace(C{"b", 2}); x != (C{"a", 1}) {
+		t.Fatalf("Replace({b 2}) = %v, want {a 1}", x)
+	}
+	h.AppendUnsorted(C{"b", 1})
+	h.Heapify()
+	if got := fmt.Sprint(h.Values()); got != "[{b 9}]" {
+		t.Fatalf("after PushPop, Replace and AppendUnsorted of key b: %v, want [{b 9}]", got)
+	}
+}
+
+func TestEachValue(t *testing.T) {
+	h := new(IntHeap)
+	Heapify(h, []int{1, 2, 6, 4})
+	var got []int
+	h.EachValue(func(v reflect.Value) bool {
+		got = append(got, int(v.Int()))
+		return true
+	})
+	checkOrder(t, got, []int{1, 2, 6, 4})
+
+	n := 0
+	h.EachValue(func(v reflect.Value) bool {
+		n++
+		return n < 2
+	})
+	if n != 2 {
+		t.Fatalf("EachValue called fn %v times after it returned false, want 2", n)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	h := NewIntHeap(Capacity(100))
+	if h.Len() != 0 || cap(h.Heap.Data) != 100 {
+		t.Fatalf("Capacity(100): Len() %v, cap %v, want 0 and 100", h.Len(), cap(h.Heap.Data))
+	}
+	for i := 0; i < 100; i++ {
+		h.Push(i)
+	}
+	if c := cap(h.Heap.Data); c != 100 {
+		t.Fatalf("cap after 100 pushes = %v, want 100", c)
+	}
+	p := newIntHeap(nil, Capacity(7))
+	if c := cap(p.Heap.Data); c != 7 {
+		t.Fatalf("Init with Capacity(7): cap %v", c)
+	}
+}
+
+// TestIndexOverflow checks the index arithmetic of up and down at the
+// largest heap sizes, where D*i+1 overflows. The heap holds a single
+// element, so down must find that the indices have no children without
+// using a child index computed after overflow.
+func TestIndexOverflow(t *testing.T) {
+	const wraps = (1<<64 + 2) / 3 // 3*wraps+1 wraps around to 3
+	for d := 2; d <= 5; d++ {
+		h := newIntHeap([]int{1}, Arity(d))
+		for _, i := range []int{(math.MaxInt-2)/d + 1, wraps, math.MaxInt - 1} {
+			if j := h.Heap.down(i, math.MaxInt); j != i {
+				t.Fatalf("d=%v: down(%v, MaxInt) moved the element to %v", d, i, j)
+			}
+		}
+		if j := h.Heap.up(0); j != 0 {
+			t.Fatalf("d=%v: up(0) = %v, want 0", d, j)
+		}
+	}
+}
+
+func TestKLargest(t *testing.T) {
+	xs := rand.New(rand.NewSource(77)).Perm(200)
+	for i := range xs {
+		xs[i] %= 113 // include duplicates
+	}
+	h := newIntHeap(xs, Stable())
+	want := append([]int(nil), xs...)
+	sort.Sort(sort.Reverse(sort.IntSlice(want)))
+	before := fmt.Sprint(h.Values())
+
+	checkOrder(t, h.KLargest(10).([]int), want[:10])
+	if after := fmt.Sprint(h.Values()); after != before {
+		t.Fatalf("KLargest changed the heap from %v to %v", before, after)
+	}
+	checkOrder(t, h.KLargest(1000).([]int), want)
+	if got := h.KLargest(0).([]int); len(got) != 0 {
+		t.Fatalf("KLargest(0) = %v, want []", got)
+	}
+}
+
+func TestWouldBeRoot(t *testing.T) {
+	h := NewIntHeap()
+	if !h.WouldBeRoot(5) {
+		t.Fatal("WouldBeRoot(5) on an empty heap = false")
+	}
+	h.PushBatch(5, 7)
+	for _, tt := range []struct {
+		x    int
+		want bool
+	}{{3, true}, {5, false}, {9, false}} {
+		if got := h.WouldBeRoot(tt.x); got != tt.want {
+			t.Fatalf("min heap [5 7]: WouldBeRoot(%v) = %v, want %v", tt.x, got, tt.want)
+		}
+	}
+	m := NewIntHeap(MaxHeap())
+	m.PushBatch(5, 7)
+	if !m.WouldBeRoot(9) || m.WouldBeRoot(3) {
+		t.Fatal("max heap [7 5]: WouldBeRoot(9) should be true and WouldBeRoot(3) false")
+	}
+	if h.Len() != 2 || m.Len() != 2 {
+		t.Fatal("WouldBeRoot changed the heap")
+	}
+}
+
+func TestAutoShrink(t *testing.T) {
+	for _, shrink := range []bool{false, true} {
+		var opts []Option
+		if shrink {
+			opts = append(opts, AutoShrink(4), Stable())
+		}
+		h := NewIntHeap(opts...)
+		for i := 0; i < 10000; i++ {
+			h.Push(i)
+		}
+		big := cap(h.Heap.Data)
+		for i := 0; i < 9990; i++ {
+			if x := h.Pop(); x != i {
+				t.Fatalf("shrink=%v: pop %v = %v", shrink, i, x)
+			}
+		}
+		if c := cap(h.Heap.Data); shrink && c > 40 || !shrink && c != big {
+			t.Fatalf("shrink=%v: cap %v after draining to 10 elements from cap %v", shrink, c, big)
+		}
+		if err := h.Validate(); err != nil {
+			t.Fatalf("shrink=%v: %v", shrink, err)
+		}
+		if x := h.Pop(); x != 9990 {
+			t.Fatalf("shrink=%v: Pop() after the drain = %v, want 9990", shrink, x)
+		}
+	}
+	if err := InitE(new(IntHeap), AutoShrink(2)); err == nil {
+		t.Fatal("AutoShrink(2) accepted")
+	}
+}
+
+func TestInitHandle(t *testing.T) {
+	p := new(IntHeap)
+	g := Init(p)
+	p.Push(5)
+	p.Push(3)
+	if n := g.Len(); n != 2 {
+		t.Fatalf("handle Len() = %v after two pushes through the struct, want 2", n)
+	}
+	p.Heap.Data[0] = reflect.ValueOf(9)
+	g.Fix(0)
+	if x := p.Pop(); x != 5 {
+		t.Fatalf("Pop() after Fix through the handle = %v, want 5", x)
+	}
+}
+
+func TestTimingHooks(t *testing.T) {
+	pushes, pops := 0, 0
+	h := NewIntHeap(OnPush(func(d time.Duration) {
+		if d < 0 {
+			t.Errorf("OnPush called with %v", d)
+		}
+		pushes++
+	}), OnPop(func(d time.Duration) {
+		if d < 0 {
+			t.Errorf("OnPop called with %v", d)
+		}
+		pops++
+	}))
+	for i := 0; i < 10; i++ {
+		h.Push(i)
+	}
+	h.Iterate(func(interface{}) bool { return true })
+	h.Pop()
+	h.TryPop()
+	if pushes != 10 || pops != 2 {
+		t.Fatalf("hooks called for %v pushes and %v pops, want 10 and 2", pushes, pops)
+	}
+}
+
+func TestHeight(t *testing.T) {
+	for _, tt := range []struct{ d, n, want int }{
+		{2, 0, 0}, {2, 1, 1}, {2, 2, 2}, {2, 3, 2}, {2, 4, 3},
+		{2, 7, 3}, {2, 8, 4}, {2, 15, 4}, {2, 16, 5},
+		{3, 1, 1}, {3, 4, 2}, {3, 5, 3}, {3, 13, 3}, {3, 14, 4},
+	} {
+		if got := FromSeed(tt.n, 1, Arity(tt.d)).Height(); got != tt.want {
+			t.Fatalf("Height() of a %v-ary heap of %v = %v, want %v", tt.d, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestNils(t *testing.T) {
+	less := func(a, b interface{}) bool { return *a.(*int) < *b.(*int) }
+	p := func(x int) *int { return &x }
+	str := func(x interface{}) string {
+		if x.(*int) == nil {
+			return "nil"
+		}
+		return fmt.Sprint(*x.(*int))
+	}
+	typ := reflect.TypeOf((*int)(nil))
+	for _, tt := range []struct {
+		policy NilPolicy
+		want   string
+	}{
+		{NilsFirst, "[nil nil nil 1 2 3]"},
+		{NilsLast, "[1 2 3 nil nil nil]"},
+	} {
+		h := NewFunc(typ, less, Nils(tt.policy))
+		h.PushBatch(p(3), nil, p(1), nil, p(2))
+		h.Push(nil)
+		var got []string
+		h.Drain(func(x interface{}) { got = append(got, str(x)) })
+		if fmt.Sprint(got) != tt.want {
+			t.Fatalf("policy %v: popped %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+
+	k := NewKeyFunc(typ, func(x interface{}) float64 { return float64(*x.(*int)) }, Nils(NilsLast))
+	k.PushBatch(nil, p(2), p(1))
+	if got := strings.Join([]string{str(k.Pop()), str(k.Pop()), str(k.Pop())}, " "); got != "1 2 nil" {
+		t.Fatalf("NewKeyFunc with NilsLast popped %v, want 1 2 nil", got)
+	}
+
+	r := NewFunc(typ, less, Nils(NilsReject))
+	if err := r.PushE(nil); !errors.Is(err, ErrNil) {
+		t.Fatalf("PushE(nil) = %v, want ErrNil", err)
+	}
+	if v := mustPanic(t, "Push(nil) with NilsReject", func() { r.Push(nil) }); fmt.Sprint(v) != "nil element of type *int" {
+		t.Fatalf("Push(nil) panicked with %q", v)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	h := FromSeed(100, 3, Stable(), LazyDelete(), CollectStats())
+	all := h.Clone().PopN(100).([]int)
+	h.RemoveValue(all[0]) // a dead element Trim must not keep
+	h.Trim(10)
+	if err := h.Validate(); err != nil || h.Len() != 10 {
+		t.Fatalf("after Trim(10): Len() %v, Validate() %v", h.Len(), err)
+	}
+	if n := h.Stats().Pops; n != 0 {
+		t.Fatalf("Trim counted %v pops, want 0", n)
+	}
+	if x, seq := h.PopSeq(); x != all[1] || seq != 1 {
+		t.Fatalf("PopSeq() after Trim = %v, %v, want %v, 1", x, seq, all[1])
+	}
+	checkOrder(t, h.PopN(20).([]int), all[2:11])
+
+	h = FromSeed(5, 3)
+	h.Trim(10)
+	if n := h.Len(); n != 5 {
+		t.Fatalf("Trim(10) of 5 elements left %v", n)
+	}
+	h.Trim(-1)
+	if n := h.Len(); n != 0 {
+		t.Fatalf("Trim(-1) left %v elements", n)
+	}
+}
+
+func TestTree(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(1, 2, 3, 4, 5)
+	if got, want := h.Tree(), "1\n  2\n    4\n    5\n  3\n"; got != want {
+		t.Fatalf("Tree() = %q, want %q", got, want)
+	}
+	if got := NewIntHeap().Tree(); got != "" {
+		t.Fatalf("Tree() of an empty heap = %q", got)
+	}
+}
+
+// EqHeap is a JobHeap that identifies jobs by name.
+type EqHeap struct {
+	GenericHeap
+	Push   func(Job)
+	Pop    func() Job
+	Remove func(int) Job
+}
+
+func (h *EqHeap) Less(a, b Job) bool { return a.Pri < b.Pri }
+func (h *EqHeap) Same(a, b Job) bool { return a.Name == b.Name }
+
+func TestSameMethod(t *testing.T) {
+	h := new(EqHeap)
+	Init(h)
+	h.Push(Job{"a", 1})
+	h.Push(Job{"b", 1})
+	h.Push(Job{"c", 1})
+	if !h.RemoveValue(Job{"b", 1}) {
+		t.Fatal("RemoveValue({b 1}) = false")
+	}
+	if h.Contains(Job{"b", 1}) {
+		t.Fatal("Contains({b 1}) after removing it")
+	}
+	if !h.Contains(Job{"c", 5}) {
+		t.Fatal("Contains({c 5}) = false, but Same matches by name")
+	}
+
+	// The hook leaves GenericHeap.Equal promoted to the user struct.
+	o := new(EqHeap)
+	Init(o)
+	o.Push(Job{"c", 1})
+	o.Push(Job{"a", 1})
+	if !h.Equal(&o.GenericHeap) {
+		t.Fatal("Equal = false for heaps holding the same jobs")
+	}
+
+	if a, c := h.Pop(), h.Pop(); a.Name+c.Name != "ac" && a.Name+c.Name != "ca" {
+		t.Fatalf("popped %v and %v, want a and c", a, c)
+	}
+
+	// Without a Same method, elements that compare equal under Less match.
+	j := new(JobHeap)
+	Init(j)
+	j.Push(Job{"a", 1})
+	if !j.Contains(Job{"zz", 1}) {
+		t.Fatal("JobHeap.Contains({zz 1}) = false, want Less equality")
+	}
+}
+
+func TestLoadSorted(t *testing.T) {
+	sorted := []int{1, 2, 2, 5, 9, 9, 12}
+	h := NewIntHeap(Debug(), Stable())
+	h.Push(100)
+	h.LoadSorted(sorted)
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	g := new(IntHeap)
+	Heapify(g, sorted)
+	checkOrder(t, h.PopN(10).([]int), g.PopAll())
+
+	mustPanic(t, "LoadSorted of an unsorted slice in debug mode", func() { h.LoadSorted([]int{3, 1}) })
+
+	// LoadSorted honors the size limits, deduplication and the counters.
+	b := NewIntHeap(Bounded(2))
+	b.LoadSorted([]int{1, 2, 3, 4})
+	checkOrder(t, b.PopN(10).([]int), []int{3, 4})
+	d := NewIntHeap(NoDuplicates())
+	d.LoadSorted(sorted)
+	checkOrder(t, d.PopN(10).([]int), []int{1, 2, 5, 9, 12})
+	s := NewIntHeap(CollectStats())
+	s.LoadSorted(sorted)
+	if n := s.Stats().Pushes; n != len(sorted) {
+		t.Fatalf("Stats().Pushes after LoadSorted of %v elements = %v", len(sorted), n)
+	}
+	m := NewIntHeap(MaxSize(2))
+	m.Push(7)
+	mustPanic(t, "LoadSorted past MaxSize", func() { m.LoadSorted([]int{1, 2, 3, 4}) })
+	checkOrder(t, m.PopN(10).([]int), []int{7})
+}
+
+// BenchmarkPointerChurn pushes and pops pointers through a heap with and
+// without per-element state. The state lives in Meta rather than in
+// wrappers around the elements, so the allocs/op of the stable and lazy
+// deletion heaps match those of the plain heap.
+func BenchmarkPointerChurn(b *testing.B) {
+	less := func(x, y interface{}) bool { return x.(*Job).Pri < y.(*Job).Pri }
+	jobs := make([]*Job, 1024)
+	for i := range jobs {
+		jobs[i] = &Job{Pri: float64(i * 7919 % 1024)}
+	}
+	for _, bm := range []struct {
+		name string
+		opts []Option
+	}{
+		{"Plain", nil},
+		{"Stable", []Option{Stable()}},
+		{"LazyDelete", []Option{LazyDelete()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			h := NewFunc(reflect.TypeOf(jobs[0]), less, bm.opts...)
+			h.PushSlice(jobs[:512])
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.Push(jobs[i%len(jobs)])
+				h.Pop()
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	h := NewIntHeap(MaxHeap())
+	h.PushBatch(1, 5, 3, 9, 7, 4, 6)
+	var got []int
+	for _, x := range h.Range(4, 7) {
+		got = append(got, x.(int))
+	}
+	sort.Ints(got)
+	checkOrder(t, got, []int{4, 5, 6, 7})
+	if n := h.Len(); n != 7 {
+		t.Fatalf("Len() after Range = %v, want 7", n)
+	}
+	if got := NewIntHeap().Range(0, 10); len(got) != 0 {
+		t.Fatalf("Range of an empty heap = %v", got)
+	}
+}
+
+func TestOnEvict(t *testing.T) {
+	var evicted []int
+	h := NewIntHeap(Bounded(3), OnEvict(func(x interface{}) { evicted = append(evicted, x.(int)) }))
+	for _, x := range []int{5, 1, 9, 3, 7, 2, 8} {
+		h.Push(x)
+	}
+	sort.Ints(evicted)
+	checkOrder(t, evicted, []int{1, 2, 3, 5})
+	checkOrder(t, h.PopN(3).([]int), []int{7, 8, 9})
+
+	// Reading the heap evicts nothing.
+	n := 0
+	k := NewIntHeap(Bounded(5), OnEvict(func(interface{}) { n++ }))
+	k.PushBatch(1, 2, 3, 4, 5)
+	k.KLargest(2)
+	if n != 0 {
+		t.Fatalf("KLargest reported %v evictions", n)
+	}
+}
+
+func TestEachIndexed(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(4, 2, 6, 1)
+	next := 0
+	h.EachIndexed(func(i int, x interface{}) bool {
+		if i != next {
+			t.Fatalf("EachIndexed visited index %v, want %v", i, next)
+		}
+		if v := h.Heap.Data[i].Interface(); v != x {
+			t.Fatalf("EachIndexed passed %v for index %v, which holds %v", x, i, v)
+		}
+		next++
+		return i < 2
+	})
+	if next != 3 {
+		t.Fatalf("EachIndexed called fn %v times, want 3", next)
+	}
+}
+
+func TestPushIndexed(t *testing.T) {
+	h := NewIntHeap()
+	for _, x := range []int{5, 3, 8, 1, 9, 2} {
+		if i, j := h.PushIndexed(x), h.IndexOf(x); i != j {
+			t.Fatalf("PushIndexed(%v) = %v, but IndexOf reports %v", x, i, j)
+		}
+	}
+	b := NewIntHeap(Bounded(2))
+	b.PushBatch(5, 6)
+	if i := b.PushIndexed(1); i != -1 {
+		t.Fatalf("PushIndexed of a rejected element = %v, want -1", i)
+	}
+	if i := b.PushIndexed(9); b.Heap.Data[i].Interface() != 9 {
+		t.Fatalf("PushIndexed(9) = %v, which holds %v", i, b.Heap.Data[i])
+	}
+}
+
+func TestReInit(t *testing.T) {
+	h := new(IntHeap)
+	Init(h)
+	h.Push(1)
+	if err := InitE(h); !errors.Is(err, ErrInitialized) {
+		t.Fatalf("second InitE = %v, want ErrInitialized", err)
+	}
+	if n := h.Len(); n != 1 {
+		t.Fatalf("rejected InitE left Len() %v, want 1", n)
+	}
+	if err := InitE(new(IntHeap)); err != nil {
+		t.Fatalf("InitE of a fresh heap = %v", err)
+	}
+	v := mustPanic(t, "second Init", func() { Init(h) })
+	if !strings.Contains(fmt.Sprint(v), "heap already initialized") {
+		t.Fatalf("second Init panicked with %q", v)
+	}
+}
+
+func TestTakeN(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 50, 99, 100, 150, 250} {
+		for _, opts := range [][]Option{nil, {Stable()}, {MaxHeap()}} {
+			h := FromSeed(100, int64(n), opts...)
+			for i := 0; i < 100; i++ {
+				h.Push(i % 7) // duplicates
+			}
+			want := h.Clone().PopN(n).([]int)
+			var got []int
+			for _, x := range h.TakeN(n) {
+				got = append(got, x.(int))
+			}
+			sort.Ints(got)
+			sort.Ints(want)
+			checkOrder(t, got, want)
+			if err := h.Validate(); err != nil {
+				t.Fatalf("TakeN(%v) left an invalid heap: %v", n, err)
+			}
+			if h.Len() != 200-len(want) {
+				t.Fatalf("TakeN(%v) left %v elements, want %v", n, h.Len(), 200-len(want))
+			}
+		}
+	}
+}
+
+func TestParentChildren(t *testing.T) {
+	h := FromSeed(6, 1)
+	for _, tt := range []struct{ i, parent int }{{0, -1}, {1, 0}, {2, 0}, {5, 2}, {6, -1}} {
+		if p := h.Parent(tt.i); p != tt.parent {
+			t.Fatalf("binary Parent(%v) = %v, want %v", tt.i, p, tt.parent)
+		}
+	}
+	if got := fmt.Sprint(h.Children(0), h.Children(2)); got != "[1 2] [5]" {
+		t.Fatalf("binary Children(0), Children(2) = %v, want [1 2] [5]", got)
+	}
+	if c := h.Children(3); c != nil {
+		t.Fatalf("binary Children(3) of a leaf = %v", c)
+	}
+
+	d := FromSeed(6, 1, Arity(3))
+	if p := d.Parent(4); p != 1 {
+		t.Fatalf("3-ary Parent(4) = %v, want 1", p)
+	}
+	if got := fmt.Sprint(d.Children(0), d.Children(1)); got != "[1 2 3] [4 5]" {
+		t.Fatalf("3-ary Children(0), Children(1) = %v, want [1 2 3] [4 5]", got)
+	}
+	if c := d.Children(2); c != nil {
+		t.Fatalf("3-ary Children(2) of a leaf = %v", c)
+	}
+	for i := 1; i < 6; i++ {
+		found := false
+		for _, c := range d.Children(d.Parent(i)) {
+			found = found || c == i
+		}
+		if !found {
+			t.Fatalf("3-ary Children(Parent(%v)) = %v does not include %v", i, d.Children(d.Parent(i)), i)
+		}
+	}
+}
+
+func TestToSlice(t *testing.T) {
+	h := NewFunc(reflect.TypeOf(Job{}), func(a, b interface{}) bool { return a.(Job).Pri < b.(Job).Pri })
+	h.PushBatch(Job{"a", 2}, Job{"b", 1})
+	s, ok := h.ToSlice().([]Job)
+	if !ok {
+		t.Fatalf("ToSlice() returned a %T, want []Job", h.ToSlice())
+	}
+	if got, want := fmt.Sprint(s), fmt.Sprint(h.Values()); got != want {
+		t.Fatalf("ToSlice() = %v, want %v in array order", got, want)
+	}
+	if s[0].Name != "b" {
+		t.Fatalf("ToSlice()[0] = %v, want the root {b 1}", s[0])
+	}
+}
+
+func TestFair(t *testing.T) {
+	h := NewFunc(reflect.TypeOf(Job{}), func(a, b interface{}) bool { return a.(Job).Pri < b.(Job).Pri }, Fair())
+	h.PushBatch(Job{"a", 1}, Job{"b", 1}, Job{"c", 1}, Job{"z", 2})
+	var got string
+	for i := 0; i < 7; i++ {
+		j := h.Pop().(Job)
+		got += j.Name
+		h.Push(j)
+	}
+	if got != "abcabca" {
+		t.Fatalf("successive pops of equal priorities = %q, want abcabca", got)
+	}
+}
+
+func TestAtSet(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(1, 2, 3, 4, 5)
+	for i := range h.Heap.Data {
+		if x := h.At(i); x != h.Heap.Data[i].Interface() {
+			t.Fatalf("At(%v) = %v, want %v", i, x, h.Heap.Data[i])
+		}
+	}
+	h.Set(0, 10)
+	if err := h.Validate(); err != nil {
+		t.Fatalf("Set(0, 10): %v", err)
+	}
+	if x := h.At(0); x != 2 {
+		t.Fatalf("At(0) after Set(0, 10) = %v, want 2", x)
+	}
+	if i := h.IndexOf(10); i < 0 {
+		t.Fatal("Set(0, 10) lost the element")
+	}
+	v := mustPanic(t, "At(5)", func() { h.At(5) })
+	if !strings.Contains(fmt.Sprint(v), "At index 5 out of range") {
+		t.Fatalf("At(5) panicked with %q", v)
+	}
+	mustPanic(t, "Set(-1, 0)", func() { h.Set(-1, 0) })
+}
+
+func TestNoDuplicates(t *testing.T) {
+	h := NewIntHeap(NoDuplicates(), MaxSize(2))
+	h.Push(3)
+	h.Push(3)
+	if n := h.Len(); n != 1 {
+		t.Fatalf("Len() after pushing 3 twice = %v, want 1", n)
+	}
+	h.PushBatch(1, 1, 3)
+	if n := h.Len(); n != 2 {
+		t.Fatalf("Len() after PushBatch(1, 1, 3) = %v, want 2", n)
+	}
+	// A duplicate is dropped before the size check, so it is not an error.
+	if err := h.PushE(1); err != nil {
+		t.Fatalf("PushE of a duplicate into a full heap = %v", err)
+	}
+	if i := h.PushIndexed(1); i != 0 {
+		t.Fatalf("PushIndexed of a duplicate = %v, want the index 0 of the present element", i)
+	}
+	checkOrder(t, h.PopN(5).([]int), []int{1, 3})
+
+	// The single-sift methods drop duplicates too.
+	r := NewIntHeap(NoDuplicates())
+	r.PushBatch(1, 5)
+	if x := r.PushPop(5); x != 1 {
+		t.Fatalf("PushPop(5) into [1 5] = %v, want 1", x)
+	}
+	checkOrder(t, r.PopAll().([]int), []int{5})
+	r.PushBatch(1, 5)
+	if x := r.Replace(5); x != 1 {
+		t.Fatalf("Replace(5) on [1 5] = %v, want 1", x)
+	}
+	checkOrder(t, r.PopAll().([]int), []int{5})
+	r.PushBatch(1, 5)
+	if x := r.Replace(1); x != 1 {
+		t.Fatalf("Replace(1) on [1 5] = %v, want 1", x)
+	}
+	checkOrder(t, r.PopAll().([]int), []int{1, 5})
+	for _, x := range []int{4, 2, 4, 2} {
+		r.AppendUnsorted(x)
+	}
+	r.Heapify()
+	checkOrder(t, r.PopAll().([]int), []int{2, 4})
+}
+
+func TestRank(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(10, 20, 20, 30, 40)
+	for _, tt := range []struct{ x, want int }{{0, 0}, {10, 0}, {15, 1}, {20, 1}, {25, 3}, {100, 5}} {
+		if got := h.Rank(tt.x); got != tt.want {
+			t.Fatalf("Rank(%v) = %v, want %v", tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestValidUnder(t *testing.T) {
+	lt := func(x, y int) bool { return x < y }
+	h := FromSeed(50, 4)
+	if !h.ValidUnder(lt) || !h.ValidUnder(func(x, y interface{}) bool { return x.(int) < y.(int) }) {
+		t.Fatal("a min heap is not valid under <")
+	}
+	if h.ValidUnder(Reverse(lt)) {
+		t.Fatal("a min heap is valid under >")
+	}
+	h.SetLess(Reverse(lt))
+	if h.ValidUnder(lt) || !h.ValidUnder(Reverse(lt)) {
+		t.Fatal("after SetLess(>) the heap should be valid under > only")
+	}
+}
+
+func TestFixAll(t *testing.T) {
+	// Up to 50 changes to a 3-ary heap of 300 restore the changed paths;
+	// 200 rebuild the whole heap.
+	for _, k := range []int{1, 2, 3, 10, 200} {
+		for seed := int64(0); seed < 20; seed++ {
+			h := FromSeed(300, seed, Arity(3))
+			r := rand.New(rand.NewSource(seed))
+			var idx []int
+			for i := 0; i < k; i++ {
+				j := r.Intn(300)
+				h.Heap.Data[j] = reflect.ValueOf(r.Int())
+				idx = append(idx, j)
+			}
+			h.FixAll(idx...)
+			if err := h.Validate(); err != nil {
+				t.Fatalf("FixAll of %v indices, seed %v: %v", k, seed, err)
+			}
+		}
+	}
+}
+
+// large is an element type big enough that copying it would dominate a
+// swap.
+type large struct {
+	Key int
+	Pad [4096]byte
+}
+
+// BenchmarkSwap compares swapping ints with swapping large structs. A
+// swap moves reflect.Value headers, so both cost the same.
+func BenchmarkSwap(b *testing.B) {
+	ints := NewIntHeap()
+	ints.PushBatch(1, 2)
+	big := NewFunc(reflect.TypeOf(large{}), func(x, y interface{}) bool { return x.(large).Key < y.(large).Key })
+	big.PushBatch(large{Key: 1}, large{Key: 2})
+	for _, bm := range []struct {
+		name string
+		h    *GenericHeap
+	}{{"Int", ints}, {"Large", big}} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bm.h.Heap.swap(0, 1)
+			}
+		})
+	}
+}
+
+func TestPopMax(t *testing.T) {
+	h := FromSeed(100, 9, LazyDelete())
+	all := h.Clone().PopN(100).([]int)
+	h.RemoveValue(all[99]) // a dead maximum PopMax must skip
+	for i := 98; i >= 0; i-- {
+		if x := h.PopMax(); x != all[i] {
+			t.Fatalf("PopMax() = %v, want %v", x, all[i])
+		}
+		if err := h.Validate(); err != nil {
+			t.Fatalf("after PopMax of %v: %v", all[i], err)
+		}
+	}
+	m := NewIntHeap(MaxHeap())
+	m.PushBatch(1, 3, 2)
+	if x := m.PopMax(); x != 3 {
+		t.Fatalf("PopMax() of a max heap = %v, want 3", x)
+	}
+}
+
+func TestMergeSlice(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(5, 1)
+	in := []int{4, 0, 9}
+	h.MergeSlice(in)
+	checkOrder(t, h.PopN(10).([]int), []int{0, 1, 4, 5, 9})
+	checkOrder(t, in, []int{4, 0, 9})
+	mustPanic(t, "MergeSlice of a []string", func() { h.MergeSlice([]string{"a"}) })
+}
+
+// TestRemoveEdges removes every index, including the last, second to
+// last and only element, from small heaps of each layout.
+func TestRemoveEdges(t *testing.T) {
+	for n := 1; n <= 6; n++ {
+		for i := 0; i < n; i++ {
+			for _, opts := range [][]Option{nil, {Stable()}, {Arity(3)}, {MaxHeap()}} {
+				for seed := int64(0); seed < 10; seed++ {
+					h := FromSeed(n, seed, opts...)
+					want := h.At(i)
+					if x := h.Remove(i); x != want {
+						t.Fatalf("n=%v seed=%v: Remove(%v) = %v, want %v", n, seed, i, x, want)
+					}
+					if err := h.Validate(); err != nil || h.Len() != n-1 {
+						t.Fatalf("n=%v seed=%v: after Remove(%v): Len() %v, Validate() %v", n, seed, i, h.Len(), err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMapInPlace(t *testing.T) {
+	h := NewIntHeap()
+	h.PushBatch(1, 2, 3, 4, 5)
+	h.MapInPlace(func(x interface{}) interface{} { return 10 - x.(int)*2 })
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, h.PopN(10).([]int), []int{0, 2, 4, 6, 8})
+}
+
+func TestCap(t *testing.T) {
+	h := newIntHeap([]int{0, 1, 2})
+	h.Reserve(100)
+	if c := h.Cap(); c < 100 {
+		t.Fatalf("Cap() after Reserve(100) = %v, want at least 100", c)
+	}
+	h.Compact()
+	if c := h.Cap(); c != 3 {
+		t.Fatalf("Cap() after Compact = %v, want 3", c)
+	}
+}
+
+func TestPopOrZero(t *testing.T) {
+	h := newIntHeap(nil)
+	if x, ok := h.PopOrZero().(int); !ok || x != 0 {
+		t.Fatalf("PopOrZero() of an empty int heap = %#v, want int 0", h.PopOrZero())
+	}
+	h.Push(4)
+	if x := h.PopOrZero(); x != 4 {
+		t.Fatalf("PopOrZero() = %v, want 4", x)
+	}
+	j := new(JobHeap)
+	Init(j)
+	if x, ok := j.PopOrZero().(Job); !ok || x != (Job{}) {
+		t.Fatalf("PopOrZero() of an empty Job heap = %#v, want Job{}", j.PopOrZero())
+	}
+}
+
+func TestRemoveGreater(t *testing.T) {
+	h := newIntHeap([]int{5, 1, 9, 3, 7, 2, 8})
+	var got []int
+	for _, x := range h.RemoveGreater(4) {
+		got = append(got, x.(int))
+	}
+	sort.Ints(got)
+	checkOrder(t, got, []int{5, 7, 8, 9})
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, h.PopAll(), []int{1, 2, 3})
+}
+
+func TestRemoveIdentity(t *testing.T) {
+	j := new(JobHeap)
+	Init(j, RemoveIdentity(func(e, x interface{}) bool { return e.(Job).Name == x.(Job).Name }))
+	for _, n := range []string{"a", "b", "c", "d"} {
+		j.Push(Job{n, 1})
+	}
+	if !j.RemoveValue(Job{"c", 1}) {
+		t.Fatal("RemoveValue({c 1}) = false")
+	}
+	if j.RemoveValue(Job{"c", 1}) {
+		t.Fatal("second RemoveValue({c 1}) = true")
+	}
+	for j.Len() > 0 {
+		if x := j.Pop(); x.Name == "c" {
+			t.Fatalf("popped %v after removing it", x)
+		}
+	}
+
+	// By default RemoveValue takes the equal element closest to the root.
+	h := new(JobHeap)
+	Init(h)
+	for _, n := range []string{"a", "b", "c"} {
+		h.Push(Job{n, 1})
+	}
+	root := h.Heap.Data[0].Interface().(Job)
+	h.RemoveValue(Job{"z", 1})
+	for h.Len() > 0 {
+		if x := h.Pop(); x == root {
+			t.Fatalf("RemoveValue({z 1}) left the root %v in the heap", root)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	h := newIntHeap([]int{5, 1, 9, 3})
+	c := h.Cursor()
+	var got []int
+	for i := 0; i < 2; i++ {
+		x, ok := c.Next()
+		if !ok {
+			t.Fatalf("Next() %v reported no element", i)
+		}
+		got = append(got, x.(int))
+	}
+	checkOrder(t, got, []int{1, 3})
+	if n := h.Len(); n != 4 {
+		t.Fatalf("Len() after consuming the cursor = %v, want 4", n)
+	}
+	c.Next()
+	c.Next()
+	if x, ok := c.Next(); ok {
+		t.Fatalf("Next() past the end = %v, true", x)
+	}
+	checkOrder(t, h.PopAll(), []int{1, 3, 5, 9})
+}
+
+func TestModifiedDuringIteration(t *testing.T) {
+	h := newIntHeap([]int{5, 1, 9, 3})
+	n := 0
+	h.EachValue(func(reflect.Value) bool { n++; return true })
+	if n != 4 {
+		t.Fatalf("EachValue visited %v elements, want 4", n)
+	}
+	for name, iterate := range map[string]func(){
+		"EachValue":   func() { h.EachValue(func(reflect.Value) bool { h.Pop(); return true }) },
+		"EachIndexed": func() { h.EachIndexed(func(int, interface{}) bool { h.Push(0); return true }) },
+	} {
+		if v := mustPanic(t, "mutating inside "+name, iterate); v != "heap modified during iteration" {
+			t.Fatalf("mutating inside %v panicked with %q", name, v)
+		}
+	}
+}
+
+// TestMembershipIndex checks Contains against a reference multiset over
+// a random mix of the operations that maintain the index.
+func TestMembershipIndex(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		opts := []Option{MembershipIndex(func(x interface{}) interface{} { return x })}
+		if lazy {
+			opts = append(opts, LazyDelete())
+		}
+		h := NewIntHeap(opts...)
+		ref := map[int]int{}
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 5000; i++ {
+			x := r.Intn(50)
+			switch r.Intn(6) {
+			case 0, 1:
+				h.Push(x)
+				ref[x]++
+			case 2:
+				if h.Len() > 0 {
+					ref[h.Pop().(int)]--
+				}
+			case 3:
+				if h.RemoveValue(x) {
+					ref[x]--
+				}
+			case 4:
+				for _, y := range h.RemoveGreater(45) {
+					ref[y.(int)]--
+				}
+			case 5:
+				if got := h.Clone().Contains(x); got != (ref[x] > 0) {
+					t.Fatalf("lazy=%v step %v: clone Contains(%v) = %v", lazy, i, x, got)
+				}
+			}
+			for y := 0; y < 50; y++ {
+				if got := h.Contains(y); got != (ref[y] > 0) {
+					t.Fatalf("lazy=%v step %v: Contains(%v) = %v, want %v", lazy, i, y, got, ref[y] > 0)
+				}
+			}
+		}
+	}
+
+	id := func(x interface{}) interface{} { return x }
+
+	// A full bounded heap rejecting 5 must not uncount the 5 it holds.
+	b := NewIntHeap(Bounded(2), MembershipIndex(id))
+	b.PushBatch(5, 6, 5)
+	if !b.Contains(5) || !b.Contains(6) {
+		t.Fatalf("bounded heap %v: Contains(5) %v, Contains(6) %v, want true", b.Values(), b.Contains(5), b.Contains(6))
+	}
+
+	// Heapify recounts the keys after direct edits to Data, whether or
+	// not they change its length.
+	for _, opts := range [][]Option{nil, {Stable()}} {
+		h := NewIntHeap(append(opts, MembershipIndex(id))...)
+		h.PushSlice([]int{1, 2, 3})
+		h.Heap.Data = h.Heap.Data[:2]
+		h.Heapify()
+		h.RemoveValue(1)
+		if h.Contains(1) || h.Contains(3) || !h.Contains(2) {
+			t.Fatalf("%v after truncating Data and removing 1: Contains(1) %v, Contains(2) %v, Contains(3) %v", h.Values(), h.Contains(1), h.Contains(2), h.Contains(3))
+		}
+		h.Heap.Data[0] = reflect.ValueOf(7)
+		h.Heapify()
+		if h.Contains(2) || !h.Contains(7) {
+			t.Fatalf("%v after replacing 2 by 7: Contains(2) %v, Contains(7) %v", h.Values(), h.Contains(2), h.Contains(7))
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	even := func(x interface{}) bool { return x.(int)%2 == 0 }
+	h := FromSeed(200, 3)
+	before := fmt.Sprint(h.Values())
+	k, s := h.Split(even)
+	if after := fmt.Sprint(h.Values()); after != before {
+		t.Fatal("Split changed the original heap")
+	}
+	if n := k.Len() + s.Len(); n != 200 {
+		t.Fatalf("Split into %v and %v elements, want 200 in total", k.Len(), s.Len())
+	}
+	for name, part := range map[string]*GenericHeap{"kept": k, "split off": s} {
+		if err := part.Validate(); err != nil {
+			t.Fatalf("%v heap: %v", name, err)
+		}
+	}
+	if n := k.CountFunc(func(x interface{}) bool { return !even(x) }); n != 0 {
+		t.Fatalf("the kept heap holds %v odd elements", n)
+	}
+	if n := s.CountFunc(even); n != 0 {
+		t.Fatalf("the split off heap holds %v even elements", n)
+	}
+}
+
+func TestContextFunc(t *testing.T) {
+	less := func(ctx, a, b interface{}) bool {
+		w := ctx.(map[string]int)
+		return w[a.(string)] < w[b.(string)]
+	}
+	h := NewContextFunc(reflect.TypeOf(""), less, map[string]int{"a": 1, "b": 2, "c": 3})
+	h.PushBatch("c", "a", "b")
+	if x, _ := h.Peek(); x != "a" {
+		t.Fatalf("Peek() = %v, want a, the lightest", x)
+	}
+	h.SetContext(map[string]int{"a": 3, "b": 2, "c": 1})
+	var got []string
+	for h.Len() > 0 {
+		got = append(got, h.Pop().(string))
+	}
+	if s := strings.Join(got, ""); s != "cba" {
+		t.Fatalf("popped %q after SetContext, want cba", s)
+	}
+}
+
+func TestTop(t *testing.T) {
+	h := NewIntHeap()
+	if x, i := h.Top(); i != -1 {
+		t.Fatalf("Top() of an empty heap = %v, %v, want index -1", x, i)
+	}
+	h.PushSlice([]int{4, 2, 8})
+	// Bump the root above the next smallest element.
+	if x, i := h.Top(); x.(int) < 5 {
+		h.Set(i, x.(int)+10)
+	}
+	if x, i := h.Top(); x != 4 || i != 0 {
+		t.Fatalf("Top() after updating the root = %v, %v, want 4, 0", x, i)
+	}
+	checkOrder(t, h.PopN(3).([]int), []int{4, 8, 12})
+}
+
+func TestCheckLess(t *testing.T) {
+	// rock < paper < scissors < rock
+	beats := map[string]string{"rock": "paper", "paper": "scissors", "scissors": "rock"}
+	less := func(a, b interface{}) bool { return beats[a.(string)] == b.(string) }
+	h := NewFunc(reflect.TypeOf(""), less)
+	h.PushSlice([]string{"rock", "paper", "scissors"})
+	if err := h.CheckLess(100); err == nil || !strings.Contains(err.Error(), "transitive") {
+		t.Fatalf("CheckLess of a cyclic Less = %v, want a transitivity error", err)
+	}
+	if err := FromSeed(100, 1).CheckLess(100); err != nil {
+		t.Fatalf("CheckLess of < on ints = %v", err)
+	}
+	d := NewFunc(reflect.TypeOf(""), less, Debug())
+	mustPanic(t, "pushing a cyclic Less in debug mode", func() {
+		d.PushSlice([]string{"rock", "paper", "scissors"})
+	})
+}
+
+func TestDrainBatches(t *testing.T) {
+	h := NewIntHeap()
+	h.PushSlice([]int{7, 3, 9, 1, 5, 2, 8})
+	var sizes, all []int
+	h.DrainBatches(3, func(b []interface{}) {
+		sizes = append(sizes, len(b))
+		for _, x := range b {
+			all = append(all, x.(int))
+		}
+	})
+	checkOrder(t, sizes, []int{3, 3, 1})
+	checkOrder(t, all, []int{1, 2, 3, 5, 7, 8, 9})
+	if n := h.Len(); n != 0 {
+		t.Fatalf("Len() after DrainBatches = %v, want 0", n)
+	}
+}
+
+func TestIsInitialized(t *testing.T) {
+	h := new(IntHeap)
+	for _, x := range []interface{}{h, 3, (*IntHeap)(nil)} {
+		if IsInitialized(x) {
+			t.Fatalf("IsInitialized(%#v) = true before Init", x)
+		}
+	}
+	Init(h)
+	if !IsInitialized(h) {
+		t.Fatal("IsInitialized = false after Init")
+	}
+}
+
+func TestRearity(t *testing.T) {
+	h := FromSeed(500, 9)
+	want := h.Clone()
+	h.Rearity(4)
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Heap.D != 4 {
+		t.Fatalf("arity after Rearity(4) = %v", h.Heap.D)
+	}
+	for h.Len() > 0 {
+		if x, y := h.Pop(), want.Pop(); x != y {
+			t.Fatalf("the 4-ary heap popped %v, the binary heap %v", x, y)
+		}
+	}
+}
+
+func TestEmptyBulk(t *testing.T) {
+	var nilInts []int
+	empties := []interface{}{nil, nilInts, []int{}}
+	h := NewIntHeap(Debug())
+	h.Push(3)
+	mods := h.Heap.Mods
+	for _, s := range empties {
+		h.PushSlice(s)
+		h.MergeSlice(s)
+	}
+	h.PushBatch()
+	if h.Len() != 1 || h.Heap.Mods != mods {
+		t.Fatalf("empty bulk pushes changed the heap: Len() %v, Mods %v, want 1, %v", h.Len(), h.Heap.Mods, mods)
+	}
+	h.PushSlice([]int{1, 2})
+	h.MergeSlice([]int{0})
+	checkOrder(t, h.PopN(10).([]int), []int{0, 1, 2, 3})
+
+	for _, s := range empties {
+		g := new(IntHeap)
+		Heapify(g, s)
+		if n := g.Len(); n != 0 {
+			t.Fatalf("Heapify(%#v) built a heap of %v", s, n)
+		}
+	}
+	g := new(IntHeap)
+	Heapify(g, []int{2, 1})
+	checkOrder(t, g.PopAll(), []int{1, 2})
+}
+
+func TestKthSmallest(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{{"binary", nil}, {"3-ary", []Option{Arity(3)}}, {"lazy", []Option{LazyDelete()}}} {
+		h := NewIntHeap(tt.opts...)
+		r := rand.New(rand.NewSource(4))
+		var xs []int
+		for i := 0; i < 300; i++ {
+			x := r.Intn(100)
+			h.Push(x)
+			xs = append(xs, x)
+		}
+		for _, x := range xs[:50] {
+			h.RemoveValue(x)
+		}
+		xs = xs[50:]
+		sort.Ints(xs)
+		for k := 1; k <= len(xs); k += 7 {
+			if got := h.KthSmallest(k); got != xs[k-1] {
+				t.Fatalf("%v: KthSmallest(%v) = %v, want %v", tt.name, k, got, xs[k-1])
+			}
+		}
+		if n := h.Len(); n != 250 {
+			t.Fatalf("%v: Len() after KthSmallest = %v, want 250", tt.name, n)
+		}
+		mustPanic(t, "KthSmallest(0)", func() { h.KthSmallest(0) })
+		mustPanic(t, "KthSmallest(Len()+1)", func() { h.KthSmallest(251) })
+	}
+}
+
+func TestPopSeq(t *testing.T) {
+	h := FromSeed(10, 2)
+	_, a := h.PopSeq()
+	h.Pop() // counts without being reported
+	_, b := h.PopSeq()
+	_, c := h.PopSeq()
+	if a != 1 || b != 3 || c != 4 {
+		t.Fatalf("PopSeq() around a Pop = %v, %v, %v, want 1, 3, 4", a, b, c)
+	}
+
+	// Every method that pops counts, and Stats agrees.
+	s := NewIntHeap(CollectStats())
+	s.PushSlice([]int{1, 2, 3, 4, 5, 6, 7, 8})
+	s.PopMax()
+	s.TakeN(2)
+	s.TakeN(4)
+	if _, seq := s.PopSeq(); seq != 8 || s.Stats().Pops != 8 {
+		t.Fatalf("PopSeq() after PopMax and TakeN of 6 = %v, Stats().Pops %v, want 8 and 8", seq, s.Stats().Pops)
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	h := NewIntHeap()
+	h.PushSlice([]int{5, 1, 9, 3, 3})
+	got := h.ContainsAll(3, 4, 9, 0, 1, 3, 10)
+	if s := fmt.Sprint(got); s != "[true false true false true true false]" {
+		t.Fatalf("ContainsAll = %v", s)
+	}
+
+	r := rand.New(rand.NewSource(5))
+	l := NewIntHeap(LazyDelete())
+	for i := 0; i < 200; i++ {
+		l.Push(r.Intn(300))
+	}
+	for i := 0; i < 50; i++ {
+		l.RemoveValue(r.Intn(300))
+	}
+	var xs []interface{}
+	for i := 0; i < 100; i++ {
+		xs = append(xs, r.Intn(300))
+	}
+	for i, ok := range l.ContainsAll(xs...) {
+		if ok != l.Contains(xs[i]) {
+			t.Fatalf("ContainsAll reports %v for %v, Contains %v", ok, xs[i], !ok)
+		}
+	}
+}
+
+func TestLastCompareCount(t *testing.T) {
+	if n := NewIntHeap().LastCompareCount(); n != 0 {
+		t.Fatalf("LastCompareCount() of a new heap = %v", n)
+	}
+	avg := func(n int) float64 {
+		h := FromSeed(n, 1, CollectStats())
+		total := 0
+		for i := 0; i < 100; i++ {
+			h.Pop()
+			total += h.LastCompareCount()
+		}
+		return float64(total) / 100
+	}
+	// A pop sifts down about log2(n) levels, so squaring n about doubles
+	// the count.
+	a, b := avg(1<<8), avg(1<<16)
+	if b < 1.5*a || b > 3*a {
+		t.Fatalf("average comparisons per Pop: %v for 2^8 elements, %v for 2^16, want about twice as many", a, b)
+	}
+}
+
+func TestPopInto(t *testing.T) {
+	h := NewIntHeap()
+	h.PushSlice([]int{3, 1, 2})
+	var x int
+	h.PopInto(&x)
+	if x != 1 {
+		t.Fatalf("PopInto(*int) stored %v, want 1", x)
+	}
+	var y interface{}
+	h.PopInto(&y)
+	if y != 2 {
+		t.Fatalf("PopInto(*interface{}) stored %v, want 2", y)
+	}
+	for _, bad := range []interface{}{x, (*int)(nil), new(string)} {
+		mustPanic(t, fmt.Sprintf("PopInto(%#v)", bad), func() { h.PopInto(bad) })
+	}
+	if n := h.Len(); n != 1 {
+		t.Fatalf("rejected PopInto calls popped elements: Len() %v, want 1", n)
+	}
+}
+
+// BenchmarkPopInto compares PopInto with Pop, which boxes each element
+// it returns in an interface value.
+func BenchmarkPopInto(b *testing.B) {
+	b.Run("Pop", func(b *testing.B) {
+		h := FromSeed(1000, 1)
+		var sink interface{}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sink = h.Pop()
+			h.AppendUnsorted(1 << 40)
+			h.SiftUp(h.Len() - 1)
+		}
+		_ = sink
+	})
+	b.Run("PopInto", func(b *testing.B) {
+		h := FromSeed(1000, 1)
+		var x int
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.PopInto(&x)
+			h.AppendUnsorted(1 << 40)
+			h.SiftUp(h.Len() - 1)
+		}
+	})
+}
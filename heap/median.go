@@ -0,0 +1,48 @@
+package heap
+
+import "reflect"
+
+// RunningMedian tracks the median of a stream of elements with two heaps:
+// a max-heap of the lower half and a min-heap of the upper half.
+type RunningMedian struct {
+	lower *GenericHeap
+	upper *GenericHeap
+}
+
+func NewRunningMedian(elemType reflect.Type, less func(a, b interface{}) bool) *RunningMedian {
+	return &RunningMedian{
+		lower: NewFunc(elemType, less, MaxHeap()),
+		upper: NewFunc(elemType, less),
+	}
+}
+
+func (m *RunningMedian) Len() int {
+	return m.lower.Len() + m.upper.Len()
+}
+
+// Add adds x to the stream in O(log n).
+func (m *RunningMedian) Add(x interface{}) {
+	if m.lower.Len() == 0 || m.lower.WouldBeRoot(x) {
+		m.upper.Push(x)
+	} else {
+		m.lower.Push(x)
+	}
+
+	// Keep the lower half equal in size to the upper one or one larger.
+	if m.lower.Len() > m.upper.Len()+1 {
+		m.upper.Push(m.lower.Pop())
+	} else if m.upper.Len() > m.lower.Len() {
+		m.lower.Push(m.upper.Pop())
+	}
+}
+
+// Median returns the median of the elements added so far, or nil if
+// there are none. For an even count it is the lower of the two middle
+// elements.
+func (m *RunningMedian) Median() interface{} {
+	if m.lower.Len() == 0 {
+		return nil
+	}
+	x, _ := m.lower.Peek()
+	return x
+}
@@ -0,0 +1,25 @@
+package heap
+
+import "math/rand"
+import "reflect"
+import "sort"
+import "testing"
+
+func TestRunningMedian(t *testing.T) {
+	m := NewRunningMedian(reflect.TypeOf(0), func(a, b interface{}) bool { return a.(int) < b.(int) })
+	if x := m.Median(); x != nil {
+		t.Fatalf("Median() of no elements = %v, want nil", x)
+	}
+	r := rand.New(rand.NewSource(94))
+	var seen []int
+	for i := 0; i < 200; i++ {
+		x := r.Intn(61)
+		m.Add(x)
+		seen = append(seen, x)
+		s := append([]int(nil), seen...)
+		sort.Ints(s)
+		if got, want := m.Median(), s[(len(s)-1)/2]; got != want || m.Len() != len(s) {
+			t.Fatalf("after %v adds: Median() %v, Len() %v, want %v and %v", i+1, got, m.Len(), want, len(s))
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package heap
+
+import "fmt"
+import "reflect"
+
+// MergeSorted returns an iterator over the elements of heaps in global
+// heap order, which reports false once every heap is empty. The heaps must
+// hold the same element type with the same ordering, and are popped as
+// the iterator advances. Each step costs O(log n + log k) for k heaps.
+func MergeSorted(heaps ...*GenericHeap) func() (interface{}, bool) {
+	var order *heap
+	roots := NewFunc(reflect.TypeOf((*GenericHeap)(nil)), func(a, b interface{}) bool {
+		return order.lessValues(a.(*GenericHeap).Heap.Data[0], b.(*GenericHeap).Heap.Data[0])
+	})
+	for _, h := range heaps {
+		if order == nil {
+			order = h.Heap
+		} else if h.Heap.ElemType != order.ElemType || h.Heap.Max != order.Max {
+			panic(fmt.Sprintf("cannot merge heap of %v with heap of %v with a different ordering", h.Heap.ElemType, order.ElemType))
+		}
+		if h.Heap.skipDead(); h.Len() > 0 {
+			roots.Push(h)
+		}
+	}
+
+	return func() (interface{}, bool) {
+		if roots.Len() == 0 {
+			return nil, false
+		}
+		h := roots.Heap.Data[0].Interface().(*GenericHeap)
+		x := h.Pop()
+		if h.Heap.skipDead(); h.Len() > 0 {
+			roots.Fix(0)
+		} else {
+			roots.Pop()
+		}
+		return x, true
+	}
+}
@@ -0,0 +1,23 @@
+package heap
+
+import "testing"
+
+func TestMergeSorted(t *testing.T) {
+	a, b, c := NewIntHeap(), NewIntHeap(LazyDelete()), NewIntHeap()
+	a.PushBatch(9, 1, 4)
+	b.PushBatch(2, 8, 3, 0)
+	b.RemoveValue(0) // a dead element MergeSorted must skip
+	c.PushBatch(7, 5)
+	next := MergeSorted(a, b, NewIntHeap(), c)
+	var got []int
+	for x, ok := next(); ok; x, ok = next() {
+		got = append(got, x.(int))
+	}
+	checkOrder(t, got, []int{1, 2, 3, 4, 5, 7, 8, 9})
+	if n := a.Len() + b.Len() + c.Len(); n != 0 {
+		t.Fatalf("%v elements left in the merged heaps, want 0", n)
+	}
+	if x, ok := MergeSorted()(); ok {
+		t.Fatalf("MergeSorted() of no heaps returned %v", x)
+	}
+}
@@ -0,0 +1,170 @@
+package heap
+
+import "reflect"
+
+// MinMaxHeap is a double-ended heap from which both the smallest and the
+// largest element can be popped in O(log n). It orders elements on
+// alternating min and max levels: each element on an even level is the
+// smallest of its subtree and each on an odd level the largest.
+type MinMaxHeap struct {
+	h *heap
+}
+
+// NewMinMaxHeap returns a min-max heap of elemType ordered by less, which
+// may be of any form accepted by SetLess.
+func NewMinMaxHeap(elemType reflect.Type, less interface{}) *MinMaxHeap {
+	impl, err := newHeap(elemType, nil)
+	if err != nil {
+		panic(err.Error())
+	}
+	(&GenericHeap{Heap: impl}).SetLess(less)
+	return &MinMaxHeap{h: impl}
+}
+
+func (m *MinMaxHeap) Len() int {
+	return len(m.h.Data)
+}
+
+func (m *MinMaxHeap) Push(x interface{}) {
+	m.h.Data = append(m.h.Data, m.h.value(x))
+	m.bubbleUp(len(m.h.Data) - 1)
+}
+
+// Min returns the smallest element, or false if the heap is empty.
+func (m *MinMaxHeap) Min() (interface{}, bool) {
+	if len(m.h.Data) == 0 {
+		return nil, false
+	}
+	return m.h.Data[0].Interface(), true
+}
+
+// Max returns the largest element, or false if the heap is empty.
+func (m *MinMaxHeap) Max() (interface{}, bool) {
+	if len(m.h.Data) == 0 {
+		return nil, false
+	}
+	return m.h.Data[m.maxIndex()].Interface(), true
+}
+
+// PopMin removes and returns the smallest element. The heap must not be
+// empty.
+func (m *MinMaxHeap) PopMin() interface{} {
+	if len(m.h.Data) == 0 {
+		panic("PopMin called on an empty heap")
+	}
+	return m.removeAt(0)
+}
+
+// PopMax removes and returns the largest element. The heap must not be
+// empty.
+func (m *MinMaxHeap) PopMax() interface{} {
+	if len(m.h.Data) == 0 {
+		panic("PopMax called on an empty heap")
+	}
+	return m.removeAt(m.maxIndex())
+}
+
+// maxIndex returns the index of the largest element, which is the root's
+// larger child, or the root if it has none.
+func (m *MinMaxHeap) maxIndex() int {
+	switch len(m.h.Data) {
+	case 1:
+		return 0
+	case 2:
+		return 1
+	}
+	if m.less(1, 2) {
+		return 2
+	}
+	return 1
+}
+
+func (m *MinMaxHeap) removeAt(i int) interface{} {
+	n := len(m.h.Data) - 1
+	out := m.h.Data[i]
+	m.h.Data[i] = m.h.Data[n]
+	m.h.Data[n] = reflect.Value{}
+	m.h.Data = m.h.Data[:n]
+	if i < n {
+		m.trickleDown(i)
+	}
+	return out.Interface()
+}
+
+func (m *MinMaxHeap) less(i, j int) bool {
+	return m.h.userLess(m.h.Data[i], m.h.Data[j])
+}
+
+func (m *MinMaxHeap) swap(i, j int) {
+	m.h.Data[i], m.h.Data[j] = m.h.Data[j], m.h.Data[i]
+}
+
+// isMinLevel reports whether index i is on an even, min level.
+func isMinLevel(i int) bool {
+	level := 0
+	for i > 0 {
+		i = (i - 1) / 2
+		level++
+	}
+	return level%2 == 0
+}
+
+func (m *MinMaxHeap) bubbleUp(i int) {
+	if i == 0 {
+		return
+	}
+	p := (i - 1) / 2
+	min := isMinLevel(i)
+	if min && m.less(p, i) || !min && m.less(i, p) {
+		// i belongs on the levels of its parent's kind.
+		m.swap(i, p)
+		i, min = p, !min
+	}
+	for i > 2 {
+		g := ((i-1)/2 - 1) / 2 // grandparent
+		if min && !m.less(i, g) || !min && !m.less(g, i) {
+			break
+		}
+		m.swap(i, g)
+		i = g
+	}
+}
+
+func (m *MinMaxHeap) trickleDown(i int) {
+	min := isMinLevel(i)
+	// before reports whether the element at a belongs above the one at b
+	// on the levels of i's kind.
+	before := func(a, b int) bool {
+		if min {
+			return m.less(a, b)
+		}
+		return m.less(b, a)
+	}
+
+	n := len(m.h.Data)
+	for {
+		// Find the first in order among the children and grandchildren.
+		first := 2*i + 1
+		if first >= n || first < 0 {
+			return
+		}
+		j := first
+		for _, k := range []int{first + 1, 2*first + 1, 2*first + 2, 2*first + 3, 2*first + 4} {
+			if k < n && before(k, j) {
+				j = k
+			}
+		}
+
+		if !before(j, i) {
+			return
+		}
+		m.swap(i, j)
+		if j <= first+1 {
+			return // a child, whose subtree is of the other kind
+		}
+		if p := (j - 1) / 2; before(p, j) {
+			m.swap(j, p)
+		}
+		i = j
+	}
+}
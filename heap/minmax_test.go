@@ -0,0 +1,50 @@
+package heap
+
+import "math/rand"
+import "reflect"
+import "sort"
+import "testing"
+
+func TestMinMaxHeap(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	less := func(a, b int) bool { return a < b }
+	m := NewMinMaxHeap(reflect.TypeOf(0), less)
+	var ref []int
+	for step := 0; step < 5000; step++ {
+		if len(ref) == 0 || r.Intn(3) != 0 {
+			x := r.Intn(100)
+			m.Push(x)
+			ref = append(ref, x)
+			continue
+		}
+		sort.Ints(ref)
+		lo, hi := ref[0], ref[len(ref)-1]
+		if x, _ := m.Min(); x != lo {
+			t.Fatalf("step %v: Min() = %v, want %v", step, x, lo)
+		}
+		if x, _ := m.Max(); x != hi {
+			t.Fatalf("step %v: Max() = %v, want %v", step, x, hi)
+		}
+		if r.Intn(2) == 0 {
+			if x := m.PopMin(); x != lo {
+				t.Fatalf("step %v: PopMin() = %v, want %v", step, x, lo)
+			}
+			ref = ref[1:]
+		} else {
+			if x := m.PopMax(); x != hi {
+				t.Fatalf("step %v: PopMax() = %v, want %v", step, x, hi)
+			}
+			ref = ref[:len(ref)-1]
+		}
+		if m.Len() != len(ref) {
+			t.Fatalf("step %v: Len() = %v, want %v", step, m.Len(), len(ref))
+		}
+	}
+	e := NewMinMaxHeap(reflect.TypeOf(0), less)
+	if x, ok := e.Max(); ok {
+		t.Fatalf("Max() of an empty heap = %v, true", x)
+	}
+	if x, ok := e.Min(); ok {
+		t.Fatalf("Min() of an empty heap = %v, true", x)
+	}
+}
@@ -0,0 +1,38 @@
+package heap
+
+// Pair is an element of a PriorityQueue.
+type Pair struct {
+	Priority int
+	Value interface{}
+}
+
+// PriorityQueue is a ready-made heap of values ordered by priority, with
+// the lowest priority dequeued first.
+type PriorityQueue struct {
+	GenericHeap
+	Push func(Pair)
+	Pop func() (Pair, bool)
+	Remove func(int) Pair
+	Peek func() (Pair, bool)
+}
+
+func (pq *PriorityQueue) Less(a, b Pair) bool {
+	return a.Priority < b.Priority
+}
+
+func NewPriorityQueue(opts ...Option) *PriorityQueue {
+	pq := new(PriorityQueue)
+	Init(pq, opts...)
+	return pq
+}
+
+func (pq *PriorityQueue) Enqueue(value interface{}, priority int) {
+	pq.Push(Pair{Priority: priority, Value: value})
+}
+
+// Dequeue removes and returns the value with the lowest priority, or nil
+// if the queue is empty.
+func (pq *PriorityQueue) Dequeue() interface{} {
+	p, _ := pq.Pop()
+	return p.Value
+}
@@ -0,0 +1,18 @@
+package heap
+
+import "testing"
+
+func TestPriorityQueue(t *testing.T) {
+	pq := NewPriorityQueue()
+	pq.Enqueue("c", 3)
+	pq.Enqueue("a", 1)
+	pq.Enqueue("b", 2)
+	for _, want := range []string{"a", "b", "c"} {
+		if v := pq.Dequeue(); v != want {
+			t.Fatalf("Dequeue() = %v, want %v", v, want)
+		}
+	}
+	if v := pq.Dequeue(); v != nil {
+		t.Fatalf("Dequeue() on an empty queue = %v, want nil", v)
+	}
+}
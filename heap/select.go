@@ -0,0 +1,68 @@
+package heap
+
+import "fmt"
+import "reflect"
+
+// NSmallest returns a new slice holding the n smallest elements of slice
+// per less, in ascending order.
+func NSmallest(slice interface{}, n int, less func(a, b interface{}) bool) interface{} {
+	return selectN(reflect.ValueOf(slice), n, less, MaxHeap())
+}
+
+// NLargest returns a new slice holding the n largest elements of slice
+// per less, in descending order.
+func NLargest(slice interface{}, n int, less func(a, b interface{}) bool) interface{} {
+	return selectN(reflect.ValueOf(slice), n, less)
+}
+
+// selectN keeps the best n elements of s in a bounded heap whose root is
+// the worst of them, then pops them into the result from the back.
+func selectN(s reflect.Value, n int, less func(a, b interface{}) bool, opts ...Option) interface{} {
+	if s.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("type mismatch: expected a slice, got %v", typeName(s)))
+	}
+	if n > s.Len() {
+		n = s.Len()
+	}
+	if n < 0 {
+		n = 0
+	}
+	out := reflect.MakeSlice(s.Type(), n, n)
+	if n == 0 {
+		return out.Interface()
+	}
+
+	h := NewFunc(s.Type().Elem(), less, append(opts, Bounded(n))...)
+	for i := 0; i < s.Len(); i++ {
+		h.Heap.Push([]reflect.Value{s.Index(i)})
+	}
+	for i := n - 1; i >= 0; i-- {
+		out.Index(i).Set(h.Heap.Pop(nil)[0])
+	}
+	return out.Interface()
+}
+
+// SortChannel returns a channel that, once in is closed, yields every
+// value received from in in ascending order per less, and is then closed.
+func SortChannel(in <-chan interface{}, less func(a, b interface{}) bool) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		h := NewFunc(reflect.TypeOf((*interface{})(nil)).Elem(), less)
+		for x := range in {
+			h.Push(x)
+		}
+		h.Drain(func(x interface{}) {
+			out <- x
+		})
+	}()
+	return out
+}
+
+// typeName names the type of s for panic messages, including an untyped nil.
+func typeName(s reflect.Value) string {
+	if !s.IsValid() {
+		return "nil"
+	}
+	return s.Type().String()
+}
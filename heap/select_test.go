@@ -0,0 +1,51 @@
+package heap
+
+import "fmt"
+import "strings"
+import "testing"
+
+func TestNSmallest(t *testing.T) {
+	less := func(a, b interface{}) bool { return a.(int) < b.(int) }
+	s := []int{5, 2, 9, 0, 3, 8, 6, 1}
+	for _, tc := range []struct {
+		n                 int
+		smallest, largest string
+	}{
+		{3, "[0 1 2]", "[9 8 6]"},
+		{0, "[]", "[]"},
+		{-1, "[]", "[]"},
+		{100, "[0 1 2 3 5 6 8 9]", "[9 8 6 5 3 2 1 0]"},
+	} {
+		if got := fmt.Sprint(NSmallest(s, tc.n, less)); got != tc.smallest {
+			t.Fatalf("NSmallest(%v) = %v, want %v", tc.n, got, tc.smallest)
+		}
+		if got := fmt.Sprint(NLargest(s, tc.n, less)); got != tc.largest {
+			t.Fatalf("NLargest(%v) = %v, want %v", tc.n, got, tc.largest)
+		}
+	}
+	if _, ok := NSmallest(s, 2, less).([]int); !ok {
+		t.Fatalf("NSmallest returned a %T, want []int", NSmallest(s, 2, less))
+	}
+
+	for _, x := range []interface{}{nil, 5} {
+		r := mustPanic(t, fmt.Sprintf("NSmallest(%v)", x), func() { NSmallest(x, 1, less) })
+		if msg, _ := r.(string); !strings.Contains(msg, "expected a slice") {
+			t.Fatalf("NSmallest(%v) panicked with %q", x, r)
+		}
+	}
+}
+
+func TestSortChannel(t *testing.T) {
+	in := make(chan interface{})
+	go func() {
+		for _, x := range []int{5, 2, 9, 1, 7} {
+			in <- x
+		}
+		close(in)
+	}()
+	var got []int
+	for x := range SortChannel(in, func(a, b interface{}) bool { return a.(int) < b.(int) }) {
+		got = append(got, x.(int))
+	}
+	checkOrder(t, got, []int{1, 2, 5, 7, 9})
+}
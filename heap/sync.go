@@ -0,0 +1,137 @@
+package heap
+
+import "context"
+import "reflect"
+import "sync"
+
+// SyncHeap guards a GenericHeap with a mutex so it can be shared between
+// goroutines. Operations that are not wrapped here, such as Values, can be
+// used on the underlying heap while holding the lock.
+type SyncHeap struct {
+	sync.Mutex
+	h *GenericHeap
+}
+
+func NewSyncHeap(h *GenericHeap) *SyncHeap {
+	return &SyncHeap{h: h}
+}
+
+func (s *SyncHeap) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Len()
+}
+
+func (s *SyncHeap) Push(x interface{}) {
+	v := s.h.Heap.value(x)
+	s.Lock()
+	defer s.Unlock()
+	s.h.Heap.Push([]reflect.Value{v})
+}
+
+func (s *SyncHeap) Pop() interface{} {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Heap.Pop(nil)[0].Interface()
+}
+
+// PopDo pops the root and calls fn with it while still holding the lock,
+// so that no other operation can run between the two. It reports false
+// without calling fn if the heap is empty. fn must not call methods of s,
+// which would deadlock.
+func (s *SyncHeap) PopDo(fn func(x interface{})) bool {
+	s.Lock()
+	defer s.Unlock()
+	x, ok := s.h.TryPop()
+	if ok {
+		fn(x)
+	}
+	return ok
+}
+
+func (s *SyncHeap) Peek() (interface{}, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Peek()
+}
+
+func (s *SyncHeap) Remove(i int) interface{} {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Heap.Remove([]reflect.Value{reflect.ValueOf(i)})[0].Interface()
+}
+
+func (s *SyncHeap) Fix(i int) {
+	s.Lock()
+	defer s.Unlock()
+	s.h.Fix(i)
+}
+
+// SwapContents is like GenericHeap.SwapContents under a single hold of
+// the lock, so other goroutines see either the old elements or the new
+// ones and never an empty heap in between.
+func (s *SyncHeap) SwapContents(slice interface{}) interface{} {
+	vs := s.h.Heap.values(reflect.ValueOf(slice))
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Heap.swapContents(vs).Interface()
+}
+
+// Snapshot returns a copy of the elements in heap array order, taken
+// under the lock, so the caller can read it without blocking writers.
+// Elements that are pointers still refer to the values in the heap.
+func (s *SyncHeap) Snapshot() []interface{} {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Values()
+}
+
+// BlockingHeap is a concurrent priority queue whose consumers can wait
+// for elements to be pushed.
+type BlockingHeap struct {
+	mu sync.Mutex
+	cond *sync.Cond
+	h *GenericHeap
+}
+
+func NewBlockingHeap(h *GenericHeap) *BlockingHeap {
+	b := &BlockingHeap{h: h}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *BlockingHeap) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.h.Len()
+}
+
+// PushNotify pushes x and wakes one goroutine waiting in PopWait.
+func (b *BlockingHeap) PushNotify(x interface{}) {
+	v := b.h.Heap.value(x)
+	b.mu.Lock()
+	defer b.cond.Signal() // after unlocking, so the woken waiter can run
+	defer b.mu.Unlock()
+	b.h.Heap.Push([]reflect.Value{v})
+}
+
+// PopWait pops the root, waiting for an element to be pushed if the heap
+// is empty. It returns ctx.Err() if ctx is done before that happens.
+func (b *BlockingHeap) PopWait(ctx context.Context) (interface{}, error) {
+	stop := context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	})
+	defer stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.h.Len() == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		b.cond.Wait()
+	}
+	return b.h.Heap.Pop(nil)[0].Interface(), nil
+}
@@ -0,0 +1,181 @@
+package heap
+
+import "context"
+import "sort"
+import "sync"
+import "testing"
+import "time"
+
+func TestSyncHeap(t *testing.T) {
+	h := new(IntHeap)
+	Init(h)
+	s := NewSyncHeap(&h.GenericHeap)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				s.Push(g*1000 + i)
+				s.Peek()
+				s.Fix(0)
+				s.Pop()
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := s.Len(); n != 0 {
+		t.Fatalf("Len() = %v after as many pops as pushes", n)
+	}
+}
+
+func TestSyncHeapUnlocksOnPanic(t *testing.T) {
+	h := new(IntHeap)
+	Init(h)
+	s := NewSyncHeap(&h.GenericHeap)
+	mustPanic(t, "Pop on an empty SyncHeap", func() { s.Pop() })
+	mustPanic(t, "Remove(3) on an empty SyncHeap", func() { s.Remove(3) })
+
+	// The panics must not leave the mutex locked.
+	s.Push(1)
+	if x := s.Pop(); x != 1 {
+		t.Fatalf("Pop() = %v, want 1", x)
+	}
+}
+
+func TestBlockingHeap(t *testing.T) {
+	h := new(IntHeap)
+	Init(h)
+	b := NewBlockingHeap(&h.GenericHeap)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.PushNotify(7)
+	}()
+	if x, err := b.PopWait(context.Background()); err != nil || x != 7 {
+		t.Fatalf("PopWait() = %v, %v, want 7, nil", x, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if x, err := b.PopWait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("PopWait() on an empty heap = %v, %v, want DeadlineExceeded", x, err)
+	}
+}
+
+func TestBlockingHeapUnlocksOnPanic(t *testing.T) {
+	h := new(IntHeap)
+	Init(h, MaxSize(1))
+	b := NewBlockingHeap(&h.GenericHeap)
+	b.PushNotify(1)
+	mustPanic(t, "PushNotify on a full heap", func() { b.PushNotify(2) })
+
+	done := make(chan int)
+	go func() { done <- b.Len() }()
+	select {
+	case n := <-done:
+		if n != 1 {
+			t.Fatalf("Len() = %v, want 1", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Len blocked after a PushNotify panic")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := NewSyncHeap(NewIntHeap())
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				s.Push(w*1000 + i)
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			snap := s.Snapshot()
+			for j := 1; j < len(snap); j++ {
+				if snap[j].(int) < snap[(j-1)/2].(int) {
+					t.Errorf("snapshot %v is not heap ordered at index %v", snap, j)
+					return
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	<-done
+	if n := len(s.Snapshot()); n != 800 {
+		t.Fatalf("final snapshot holds %v elements, want 800", n)
+	}
+}
+
+func TestPopDo(t *testing.T) {
+	s := NewSyncHeap(NewIntHeap())
+	for i := 0; i < 1000; i++ {
+		s.Push(i)
+	}
+	seen := make([]int, 1000)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s.PopDo(func(x interface{}) { seen[x.(int)]++ }) {
+			}
+		}()
+	}
+	wg.Wait()
+	for x, n := range seen {
+		if n != 1 {
+			t.Fatalf("element %v processed %v times, want once", x, n)
+		}
+	}
+}
+
+func TestSwapContents(t *testing.T) {
+	h := NewIntHeap()
+	h.PushSlice([]int{3, 1, 2})
+	s := NewSyncHeap(h)
+	in := []int{9, 4, 7, 5}
+	old := s.SwapContents(in).([]int)
+	sort.Ints(old)
+	checkOrder(t, old, []int{1, 2, 3})
+	if err := h.Validate(); err != nil || h.Len() != 4 {
+		t.Fatalf("after SwapContents: Len() %v, Validate() %v, want 4, nil", h.Len(), err)
+	}
+	if x, _ := s.Peek(); x != 4 {
+		t.Fatalf("Peek() after SwapContents = %v, want 4", x)
+	}
+	s.Push(0)
+	if in[0] != 9 {
+		t.Fatal("SwapContents kept aliasing the caller's slice")
+	}
+	if old := h.SwapContents(nil).([]int); len(old) != 5 || h.Len() != 0 {
+		t.Fatalf("SwapContents(nil) returned %v and left %v elements", old, h.Len())
+	}
+}
+
+func TestSwapContentsTooLarge(t *testing.T) {
+	h := new(IntHeap)
+	Init(h, MaxSize(2))
+	s := NewSyncHeap(&h.GenericHeap)
+	s.Push(1)
+	mustPanic(t, "SwapContents past MaxSize", func() { s.SwapContents([]int{1, 2, 3}) })
+	mustPanic(t, "SwapContents of a []string", func() { s.SwapContents([]string{"a"}) })
+
+	done := make(chan int)
+	go func() { done <- s.Len() }()
+	select {
+	case n := <-done:
+		if n != 1 {
+			t.Fatalf("Len() after the rejected swaps = %v, want the old 1", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Len blocked after a SwapContents panic")
+	}
+}
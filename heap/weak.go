@@ -0,0 +1,77 @@
+package heap
+
+import "weak"
+
+// WeakHeap is a min-heap of pointers that does not keep its elements
+// alive: an element the rest of the program no longer refers to may be
+// garbage collected while in the heap, and is then skipped by Pop and
+// Peek. Elements are ordered by a key computed once when they are pushed,
+// since a collected element can no longer be compared.
+type WeakHeap[T any] struct {
+	h *Heap[weakEntry[T]]
+	key func(x *T) float64
+}
+
+type weakEntry[T any] struct {
+	key float64
+	ptr weak.Pointer[T]
+}
+
+// NewWeakHeap returns an empty WeakHeap ordering its elements by key.
+func NewWeakHeap[T any](key func(x *T) float64) *WeakHeap[T] {
+	return &WeakHeap[T]{
+		h: New(func(a, b weakEntry[T]) bool { return a.key < b.key }),
+		key: key,
+	}
+}
+
+// Len returns the number of elements pushed and not yet popped, which
+// includes any that have been collected but not skipped or compacted.
+func (h *WeakHeap[T]) Len() int {
+	return h.h.Len()
+}
+
+func (h *WeakHeap[T]) Push(x *T) {
+	h.h.Push(weakEntry[T]{key: h.key(x), ptr: weak.Make(x)})
+}
+
+// Pop removes and returns the live element with the smallest key,
+// discarding collected elements ahead of it. It reports false if no live
+// element is left.
+func (h *WeakHeap[T]) Pop() (*T, bool) {
+	for h.h.Len() > 0 {
+		if x := h.h.Pop().ptr.Value(); x != nil {
+			return x, true
+		}
+	}
+	return nil, false
+}
+
+// Peek is like Pop but leaves the live element in the heap.
+func (h *WeakHeap[T]) Peek() (*T, bool) {
+	for {
+		e, ok := h.h.Peek()
+		if !ok {
+			return nil, false
+		}
+		if x := e.ptr.Value(); x != nil {
+			return x, true
+		}
+		h.h.Pop()
+	}
+}
+
+// Compact drops every collected element and rebuilds the heap in O(n).
+func (h *WeakHeap[T]) Compact() {
+	live := h.h.data[:0]
+	for _, e := range h.h.data {
+		if e.ptr.Value() != nil {
+			live = append(live, e)
+		}
+	}
+	clear(h.h.data[len(live):])
+	h.h.data = live
+	for i := len(live)/2 - 1; i >= 0; i-- {
+		h.h.down(i, len(live))
+	}
+}
@@ -0,0 +1,41 @@
+package heap
+
+import "runtime"
+import "testing"
+
+// pushGarbage pushes jobs with the given priorities that nothing else
+// refers to, so the next collection frees them.
+func pushGarbage(h *WeakHeap[Job], pris ...float64) {
+	for _, p := range pris {
+		h.Push(&Job{"garbage", p})
+	}
+}
+
+func TestWeakHeap(t *testing.T) {
+	h := NewWeakHeap(func(j *Job) float64 { return j.Pri })
+	keep := &Job{"keep", 2}
+	pushGarbage(h, 1, 3)
+	h.Push(keep)
+	runtime.GC()
+	if x, ok := h.Pop(); !ok || x != keep {
+		t.Fatalf("Pop() = %v, %v, want %v ahead of the collected jobs", x, ok, keep)
+	}
+	if x, ok := h.Pop(); ok {
+		t.Fatalf("Pop() returned %v after the only live job", x)
+	}
+	if n := h.Len(); n != 0 {
+		t.Fatalf("Len() = %v after Pop skipped every collected job", n)
+	}
+
+	pushGarbage(h, 0, 1, 5)
+	h.Push(keep)
+	runtime.GC()
+	if x, ok := h.Peek(); !ok || x != keep {
+		t.Fatalf("Peek() = %v, %v, want %v", x, ok, keep)
+	}
+	h.Compact()
+	if n := h.Len(); n != 1 {
+		t.Fatalf("Len() after Compact = %v, want 1", n)
+	}
+	runtime.KeepAlive(keep)
+}